@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const censysPageSize = 100
+
+// censysEngine searches Censys hosts by favicon MD5. The API key is the
+// "API_ID:SECRET" pair from the Censys account page.
+type censysEngine struct {
+	engineBase
+}
+
+type censysAccount struct {
+	Quota struct {
+		Used      int `json:"used"`
+		Allowance int `json:"allowance"`
+	} `json:"quota"`
+}
+
+type censysService struct {
+	Port              int    `json:"port"`
+	ServiceName       string `json:"service_name"`
+	TransportProtocol string `json:"transport_protocol"`
+}
+
+type censysHit struct {
+	IP string `json:"ip"`
+	// MatchedServices lists only the services the query matched, which
+	// for a favicon query are the ones serving the icon
+	MatchedServices []censysService `json:"matched_services"`
+	Location        struct {
+		Country string `json:"country"`
+		City    string `json:"city"`
+	} `json:"location"`
+	AutonomousSystem struct {
+		ASN         int    `json:"asn"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"autonomous_system"`
+	DNS struct {
+		Names      []string `json:"names"`
+		ReverseDNS struct {
+			Names []string `json:"names"`
+		} `json:"reverse_dns"`
+	} `json:"dns"`
+	Labels        []string `json:"labels"`
+	LastUpdatedAt string   `json:"last_updated_at"`
+}
+
+type censysSearchResponse struct {
+	Result struct {
+		Total int               `json:"total"`
+		Hits  []json.RawMessage `json:"hits"`
+		Links struct {
+			Next string `json:"next"`
+		} `json:"links"`
+	} `json:"result"`
+}
+
+func (e *censysEngine) request(path string, params url.Values) (*http.Request, error) {
+	id, secret, ok := strings.Cut(e.key, ":")
+	if !ok {
+		return nil, fmt.Errorf("Censys API key must be API_ID:SECRET")
+	}
+
+	reqURL := e.baseURL + path
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(id, secret)
+	return req, nil
+}
+
+func (e *censysEngine) account() (*censysAccount, error) {
+	req, err := e.request("/v1/account", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var account censysAccount
+	if err := e.f.fetchJSON(req, e.name, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (e *censysEngine) ValidateKey() error {
+	_, err := e.account()
+	return err
+}
+
+func (e *censysEngine) Quota() (*EngineQuota, error) {
+	account, err := e.account()
+	if err != nil {
+		return nil, err
+	}
+	return &EngineQuota{Remaining: account.Quota.Allowance - account.Quota.Used, Unit: "queries"}, nil
+}
+
+func (e *censysEngine) Query(fp *Fingerprint) string {
+	return fmt.Sprintf("services.http.response.favicons.md5_hash: %s", fp.MD5)
+}
+
+func (e *censysEngine) ManualSearchURL(fp *Fingerprint) string {
+	return "https://search.censys.io/search?resource=hosts&q=" + url.QueryEscape(e.Query(fp))
+}
+
+// Censys pages with a cursor rather than a page number
+func (e *censysEngine) SearchHash(fp *Fingerprint) (*SearchResult, error) {
+	query := e.Query(fp)
+	cursor := ""
+	total := 0
+
+	return e.f.collectPages(censysPageSize, func(page int) ([]Host, int, error) {
+		if page > 1 && cursor == "" {
+			return nil, total, nil
+		}
+
+		params := url.Values{"q": {query}, "per_page": {fmt.Sprint(censysPageSize)}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		req, err := e.request("/v2/hosts/search", params)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var resp censysSearchResponse
+		if err := e.f.fetchJSON(req, e.name, &resp); err != nil {
+			return nil, 0, err
+		}
+		cursor = resp.Result.Links.Next
+		total = resp.Result.Total
+
+		var hosts []Host
+		for _, raw := range resp.Result.Hits {
+			var hit censysHit
+			if err := json.Unmarshal(raw, &hit); err != nil {
+				e.f.debug("Skipping undecodable Censys hit: %v", err)
+				continue
+			}
+
+			base := Host{
+				IP:         hit.IP,
+				Hostnames:  append(hit.DNS.ReverseDNS.Names, hit.DNS.Names...),
+				Org:        hit.AutonomousSystem.Name,
+				ISP:        hit.AutonomousSystem.Description,
+				Country:    hit.Location.Country,
+				City:       hit.Location.City,
+				LastUpdate: hit.LastUpdatedAt,
+				Tags:       hit.Labels,
+			}
+			if hit.AutonomousSystem.ASN != 0 {
+				base.ASN = fmt.Sprintf("AS%d", hit.AutonomousSystem.ASN)
+			}
+			e.f.attachRaw(&base, e.name, raw)
+
+			// One host per service that served the favicon. Without
+			// matched_services the port is unknown, so the host is kept
+			// without one rather than guessing from its HTTP services
+			if len(hit.MatchedServices) == 0 {
+				hosts = append(hosts, base)
+				continue
+			}
+			for _, service := range hit.MatchedServices {
+				// Each service gets its own lists, so merging one
+				// service's hostnames never shows up in another's
+				host := base
+				host.Hostnames = slices.Clone(base.Hostnames)
+				host.Tags = slices.Clone(base.Tags)
+				host.Port = service.Port
+				host.Transport = strings.ToLower(service.TransportProtocol)
+				hosts = append(hosts, host)
+			}
+		}
+		return hosts, total, nil
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCensysSearchSplitsServices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Port 8080 serves HTTP too, but not the favicon, so it is
+		// missing from matched_services
+		fmt.Fprint(w, `{"result": {"total": 2, "hits": [{
+			"ip": "192.0.2.10",
+			"services": [
+				{"port": 80, "service_name": "HTTP", "transport_protocol": "TCP"},
+				{"port": 22, "service_name": "SSH", "transport_protocol": "TCP"},
+				{"port": 8080, "service_name": "HTTP", "transport_protocol": "TCP"},
+				{"port": 8443, "service_name": "HTTP", "transport_protocol": "TCP"}
+			],
+			"matched_services": [
+				{"port": 80, "service_name": "HTTP", "transport_protocol": "TCP"},
+				{"port": 8443, "service_name": "HTTP", "transport_protocol": "TCP"}
+			],
+			"dns": {"names": ["www.example.com"], "reverse_dns": {"names": ["a.example.net", "b.example.net"]}},
+			"labels": ["login-page"]
+		}, {
+			"ip": "192.0.2.11",
+			"services": [{"port": 443, "service_name": "HTTP", "transport_protocol": "TCP"}]
+		}]}}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"censys"},
+		EngineKeys: map[string]string{"censys": "id:secret"},
+		EngineURLs: map[string]string{"censys": server.URL},
+	})
+
+	result, err := f.engines[0].SearchHash(&Fingerprint{MD5: "d41d8cd98f00b204e9800998ecf8427e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hosts) != 3 {
+		t.Fatalf("got %d hosts, want one per matched service plus the unmatched hit", len(result.Hosts))
+	}
+	if result.Hosts[0].Port != 80 || result.Hosts[1].Port != 8443 {
+		t.Errorf("ports = %d, %d; want 80, 8443", result.Hosts[0].Port, result.Hosts[1].Port)
+	}
+	if host := result.Hosts[2]; host.IP != "192.0.2.11" || host.Port != 0 {
+		t.Errorf("hit without matched_services = %s:%d, want 192.0.2.11 with no port", host.IP, host.Port)
+	}
+
+	// Editing one service's lists must leave the other's alone
+	first, second := &result.Hosts[0], &result.Hosts[1]
+	first.Hostnames[0] = "changed.example.com"
+	first.Tags[0] = "changed"
+	mergeHost(first, &Host{Hostnames: []string{"first.example.com"}})
+	mergeHost(second, &Host{Hostnames: []string{"second.example.com"}})
+
+	want := []string{"a.example.net", "b.example.net", "www.example.com", "second.example.com"}
+	if fmt.Sprint(second.Hostnames) != fmt.Sprint(want) {
+		t.Errorf("second service hostnames = %v, want %v", second.Hostnames, want)
+	}
+	if got := first.Hostnames[len(first.Hostnames)-1]; got != "first.example.com" {
+		t.Errorf("first service's merged hostname was overwritten with %q", got)
+	}
+	if second.Tags[0] != "login-page" {
+		t.Errorf("second service tags = %v, want [login-page]", second.Tags)
+	}
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// command is a favhash subcommand. setup registers its flags and returns
+// the function that runs it with the remaining positional arguments.
+type command struct {
+	name    string
+	args    string
+	summary string
+	help    string
+	setup   func(fs *flag.FlagSet) func(args []string) error
+}
+
+// Subcommands in the order they are listed in the help
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:    "hash",
+			args:    "<url|cidr|range|-> ...",
+			summary: "Calculate favicon hashes for targets, without searching",
+			help:    "Find, download and fingerprint each target's favicon, then print the\nmanual search URL of every -engine. No API key is needed.",
+			setup:   hashCommand,
+		},
+		{
+			name:    "search",
+			args:    "<url|cidr|range|-> ...",
+			summary: "Calculate favicon hashes and search engines for matching hosts",
+			help:    "Fingerprint each target's favicon and search every -engine for hosts\nserving the same icon. API keys come from -k, -key, <ENGINE>_API_KEY or\nthe config file.",
+			setup:   searchCommand,
+		},
+		{
+			name:    "scan",
+			args:    "[url|cidr|range|-] ...",
+			summary: "Analyze a batch of targets from -l, arguments or stdin",
+			help:    "Batch mode: targets are read from -l, the arguments and stdin, and\nanalyzed concurrently with progress reporting and a summary. Add -hash\nto skip searching.",
+			setup:   scanCommand,
+		},
+		{
+			name:    "lookup",
+			args:    "<mmh3|md5|sha1|sha256> ...",
+			summary: "Look up hashes in the fingerprint database and history",
+			help:    "Search the fingerprint database and " + historyFile + " for favicon\nhashes and print the manual search URLs. Negative MMH3 values may be\ngiven as they are; they are not taken for flags.",
+			setup:   lookupCommand,
+		},
+		{
+			name:    "history",
+			args:    "[filter] ...",
+			summary: "List and filter previously analyzed targets",
+			help:    "Show " + historyFile + ", newest first. Filters match a\nsubstring of the target or favicon URL, or a hash exactly.",
+			setup:   historyCommand,
+		},
+		{
+			name:    "file",
+			args:    "<path|dir|archive|-> ...",
+			summary: "Hash local favicon files, directories and archives",
+			help:    "Fingerprint local favicon files without network access. Directories are\nwalked recursively, zip/tar(.gz) archives are read and - reads stdin.",
+			setup:   fileCommand,
+		},
+		{
+			name:    "serve",
+			summary: "Serve hashing and lookups over a local HTTP API",
+			help:    "Endpoints:\n  GET /hash?url=<target>   fingerprint a target's favicon\n  GET /lookup?hash=<hash>  look a hash up locally\n  GET /health              liveness check",
+			setup:   serveCommand,
+		},
+		{
+			name:    "config",
+			args:    "[path|show|set <name> <value>|unset <name>]",
+			summary: "Show or change saved flag defaults and API keys",
+			help:    "Defaults are stored by flag name (e.g. engine, hash-profile, proxy) and\napply to every command that has the flag. API keys are stored as\nkey.<engine>. Command-line flags and environment variables take\nprecedence.",
+			setup:   configCommand,
+		},
+		{
+			name:    "completion",
+			args:    "<bash|zsh|fish>",
+			summary: "Generate a shell completion script",
+			help:    "Examples:\n  source <(favhash completion bash)\n  favhash completion zsh > \"${fpath[1]}/_favhash\"\n  favhash completion fish > ~/.config/fish/completions/favhash.fish",
+			setup:   completionCommand,
+		},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Build a command's flag set along with its run function
+func (c *command) flags() (*flag.FlagSet, func(args []string) error) {
+	fs := flag.NewFlagSet("favhash "+c.name, flag.ExitOnError)
+	run := c.setup(fs)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: favhash %s [options] %s\n\n%s\n", c.name, c.args, c.summary)
+		if c.help != "" {
+			fmt.Fprintf(out, "\n%s\n", c.help)
+		}
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(out, "\nOptions:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs, run
+}
+
+// Parse a command's flags, apply saved defaults and run it
+func (c *command) run(args []string) error {
+	fs, run := c.flags()
+	fs.Parse(numericArgsLast(fs, args))
+	if err := applyConfigDefaults(fs); err != nil {
+		return err
+	}
+
+	err := run(fs.Args())
+	var usage usageError
+	if errors.As(err, &usage) {
+		fs.Usage()
+		fmt.Fprintln(fs.Output())
+	}
+	return err
+}
+
+var numericArg = regexp.MustCompile(`^-?\d+$`)
+
+// Move bare numbers such as negative MMH3 values behind a "--" so the flag
+// package does not take them for flags. Values of flags that take one are
+// left in place, and scanning stops at the first other positional argument,
+// where flag parsing would stop anyway.
+func numericArgsLast(fs *flag.FlagSet, args []string) []string {
+	var flags, numbers []string
+	positional := func(rest []string) []string {
+		return append(append(append(flags, "--"), numbers...), rest...)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return positional(args[i+1:])
+		case numericArg.MatchString(arg):
+			numbers = append(numbers, arg)
+			continue
+		case len(arg) < 2 || arg[0] != '-':
+			return positional(args[i:])
+		}
+
+		flags = append(flags, arg)
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return positional(nil)
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Print the top-level help: commands, then the legacy options
+func printMainUsage(fs *flag.FlagSet) {
+	fmt.Printf(banner, version)
+	fmt.Printf("\nUsage: favhash <command> [options] [arguments]\n")
+	fmt.Printf("       favhash [options] <url|cidr|range|-> ...\n\n")
+	fmt.Printf("Commands:\n")
+	for _, cmd := range commands {
+		fmt.Printf("  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Printf("  %-11s %s\n", "help", "Show help for a command")
+	fmt.Printf("\nRun 'favhash help <command>' for a command's options.\n")
+	fmt.Printf("\nWithout a command, favhash searches the targets (or only hashes them\nwith -hash) using these options:\n")
+	fs.SetOutput(os.Stdout)
+	fs.PrintDefaults()
+	fmt.Printf("\nExamples:\n")
+	fmt.Printf("  favhash hash example.com\n")
+	fmt.Printf("  favhash search -k YOUR_SHODAN_KEY example.com\n")
+	fmt.Printf("  favhash search -k YOUR_SHODAN_KEY -o json -t 15s example.com\n")
+	fmt.Printf("  favhash search -k YOUR_SHODAN_KEY -count-only example.com\n")
+	fmt.Printf("  favhash search -engine shodan,fofa -key fofa=YOUR_FOFA_KEY example.com\n")
+	fmt.Printf("  favhash search -k YOUR_SHODAN_KEY -proxy http://127.0.0.1:8080 example.com\n")
+	fmt.Printf("  favhash scan -hash -o csv -fields target,hash,md5 -l targets.txt\n")
+	fmt.Printf("  favhash scan -k YOUR_SHODAN_KEY -o jsonl -l targets.txt | jq 'select(.type == \"match\")'\n")
+	fmt.Printf("  subfinder -d example.com -silent | favhash hash\n")
+	fmt.Printf("  favhash hash -silent -l targets.txt | sort | uniq -c\n")
+	fmt.Printf("  favhash scan -k YOUR_SHODAN_KEY -report report.html -l targets.txt\n")
+	fmt.Printf("  favhash search -k YOUR_SHODAN_KEY -stix indicators.json example.com\n")
+	fmt.Printf("  favhash hash 192.168.1.0/24\n")
+	fmt.Printf("  favhash lookup 81586312\n")
+	fmt.Printf("  favhash file -o csv ./phishing-kits/\n")
+	fmt.Printf("  favhash config set key.shodan YOUR_SHODAN_KEY\n")
+	fmt.Printf("  favhash -k YOUR_SHODAN_KEY example.com    (legacy form)\n")
+}
+
+// Show the help for a command, or the top-level help
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		fs, _ := legacyFlags()
+		printMainUsage(fs)
+		return nil
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	fs, _ := cmd.flags()
+	fs.SetOutput(os.Stdout)
+	fs.Usage()
+	return nil
+}
+
+// Flags of the legacy "favhash [options] <target>" form, which keeps
+// working as an alias for search and hash -hash
+func legacyFlags() (*flag.FlagSet, func(args []string) error) {
+	fs := flag.NewFlagSet("favhash", flag.ExitOnError)
+	a := &analysisFlags{}
+	a.registerCommon(fs)
+	a.registerOutput(fs)
+	a.registerHTTP(fs)
+	a.registerTargets(fs)
+	a.registerDiscovery(fs)
+	a.registerEngines(fs, "Search engines to query, comma-separated ("+strings.Join(engineNames(), ", ")+")")
+	a.registerSearch(fs)
+	hashOnly := fs.Bool("hash", false, "Only calculate hash without Shodan search")
+	help := fs.Bool("h", false, "Show help")
+	fs.Usage = func() { printMainUsage(fs) }
+
+	return fs, func(args []string) error {
+		if *help {
+			fs.Usage()
+			return nil
+		}
+		return a.runTargets(args, *hashOnly, false)
+	}
+}
+
+func runLegacy(args []string) error {
+	fs, run := legacyFlags()
+	fs.Parse(numericArgsLast(fs, args))
+	if err := applyConfigDefaults(fs); err != nil {
+		return err
+	}
+
+	err := run(fs.Args())
+	if errors.Is(err, errNoTargets) {
+		fs.Usage()
+		return nil
+	}
+	return err
+}
+
+func hashCommand(fs *flag.FlagSet) func(args []string) error {
+	a := &analysisFlags{}
+	a.registerCommon(fs)
+	a.registerOutput(fs)
+	a.registerHTTP(fs)
+	a.registerTargets(fs)
+	a.registerDiscovery(fs)
+	a.registerEngines(fs, "Engines to print manual search URLs for, comma-separated ("+strings.Join(engineNames(), ", ")+")")
+	return func(args []string) error {
+		return a.runTargets(args, true, false)
+	}
+}
+
+func searchCommand(fs *flag.FlagSet) func(args []string) error {
+	a := &analysisFlags{}
+	a.registerCommon(fs)
+	a.registerOutput(fs)
+	a.registerHTTP(fs)
+	a.registerTargets(fs)
+	a.registerDiscovery(fs)
+	a.registerEngines(fs, "Search engines to query, comma-separated ("+strings.Join(engineNames(), ", ")+")")
+	a.registerSearch(fs)
+	return func(args []string) error {
+		return a.runTargets(args, false, false)
+	}
+}
+
+func scanCommand(fs *flag.FlagSet) func(args []string) error {
+	a := &analysisFlags{}
+	a.registerCommon(fs)
+	a.registerOutput(fs)
+	a.registerHTTP(fs)
+	a.registerTargets(fs)
+	a.registerDiscovery(fs)
+	a.registerEngines(fs, "Search engines to query, comma-separated ("+strings.Join(engineNames(), ", ")+")")
+	a.registerSearch(fs)
+	hashOnly := fs.Bool("hash", false, "Only calculate hashes without searching")
+	return func(args []string) error {
+		return a.runTargets(args, *hashOnly, true)
+	}
+}
+
+// usageError is a command line mistake; the command's usage is shown
+// along with it
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// Returned when a target command is run without any target
+var errNoTargets = usageError("no targets given")
+
+// analysisFlags holds the flags of the commands that analyze targets.
+// Each command registers the groups it needs; unregistered flags keep
+// their zero values.
+type analysisFlags struct {
+	debug     bool
+	noColor   bool
+	noHistory bool
+
+	outputFormat string
+	fields       string
+	silent       bool
+	reportFile   string
+	stixFile     string
+	saveResults  bool
+
+	timeout    time.Duration
+	retryCount int
+	retryDelay time.Duration
+	proxyURL   string
+	noRedirect bool
+	customUA   string
+	uaFile     string
+	uaMode     string
+	hostConns  int
+	rateLimit  float64
+
+	listFile    string
+	concurrency int
+
+	pathsFile      string
+	replacePaths   bool
+	allIcons       bool
+	variants       bool
+	hashProfile    string
+	fingerprintDBs fileValues
+
+	engineList  string
+	shodanKey   string
+	engineKeys  engineValues
+	engineURLs  engineValues
+	pages       int
+	maxResults  int
+	creditFloor int
+	resume      bool
+	rawResults  bool
+	countOnly   bool
+}
+
+func (a *analysisFlags) registerCommon(fs *flag.FlagSet) {
+	fs.BoolVar(&a.debug, "debug", false, "Enable debug output")
+	fs.BoolVar(&a.noColor, "no-color", false, "Disable colored output")
+	fs.BoolVar(&a.noHistory, "no-history", false, "Disable search history")
+}
+
+func (a *analysisFlags) registerOutput(fs *flag.FlagSet) {
+	fs.StringVar(&a.outputFormat, "o", "text", "Output format (text, json, yaml, jsonl, csv, tsv)")
+	fs.StringVar(&a.fields, "fields", "", "Comma-separated columns for -o csv/tsv (default: all)")
+	fs.BoolVar(&a.silent, "silent", false, "Only print the favicon hash, one per line (no banner or status)")
+	fs.StringVar(&a.reportFile, "report", "", "Write an HTML or Markdown report (report.html, report.md)")
+	fs.StringVar(&a.stixFile, "stix", "", "Write hashes and matched hosts as a STIX 2.1 bundle to this file")
+	fs.BoolVar(&a.saveResults, "save", false, "Save results to file")
+}
+
+func (a *analysisFlags) registerHTTP(fs *flag.FlagSet) {
+	fs.DurationVar(&a.timeout, "t", 5*time.Second, "Timeout for requests")
+	fs.IntVar(&a.retryCount, "r", 3, fmt.Sprintf("Number of retries for failed requests (max %d)", maxRetries))
+	fs.DurationVar(&a.retryDelay, "delay", 2*time.Second, "Base delay between retries (doubled on each attempt)")
+	fs.StringVar(&a.proxyURL, "proxy", "", "Proxy URL (e.g., http://127.0.0.1:8080)")
+	fs.BoolVar(&a.noRedirect, "no-redirect", false, "Disable following redirects")
+	fs.StringVar(&a.customUA, "ua", "", "Custom User-Agent string")
+	fs.StringVar(&a.uaFile, "ua-file", "", "File with User-Agents to rotate through (one per line)")
+	fs.StringVar(&a.uaMode, "ua-mode", defaultUserAgentMode, "User-Agent selection (random, rotate, host)")
+	fs.IntVar(&a.hostConns, "host-limit", defaultHostConcurrency, "Maximum concurrent requests per host")
+	fs.Float64Var(&a.rateLimit, "rate", 0, "Maximum requests per second across all targets (0 = unlimited)")
+}
+
+func (a *analysisFlags) registerTargets(fs *flag.FlagSet) {
+	fs.StringVar(&a.listFile, "l", "", "File with targets to analyze (one per line, CIDRs and IP ranges allowed)")
+	fs.IntVar(&a.concurrency, "c", defaultConcurrency, "Number of targets analyzed concurrently")
+}
+
+func (a *analysisFlags) registerDiscovery(fs *flag.FlagSet) {
+	fs.StringVar(&a.pathsFile, "paths", "", "Wordlist of extra favicon paths to probe (one per line)")
+	fs.BoolVar(&a.replacePaths, "paths-only", false, "Probe only the -paths wordlist instead of extending the built-in paths")
+	fs.BoolVar(&a.allIcons, "all", false, "Enumerate and hash every unique favicon candidate")
+	fs.BoolVar(&a.variants, "variants", false, "Also probe www/apex and http/https variants of the target host")
+	fs.StringVar(&a.hashProfile, "hash-profile", defaultHashProfile, "Hash encoding profile ("+strings.Join(hashProfileNames(), ", ")+")")
+	fs.Var(&a.fingerprintDBs, "fingerprints", "Extra YAML or JSON fingerprint database to load (repeatable)")
+}
+
+func (a *analysisFlags) registerEngines(fs *flag.FlagSet, usage string) {
+	fs.StringVar(&a.engineList, "engine", defaultEngine, usage)
+}
+
+func (a *analysisFlags) registerSearch(fs *flag.FlagSet) {
+	a.engineKeys = engineValues{}
+	a.engineURLs = engineValues{}
+	fs.StringVar(&a.shodanKey, "k", "", "Shodan API key")
+	fs.Var(a.engineKeys, "key", "API key for an engine as engine=KEY (repeatable, e.g. -key censys=ID:SECRET)")
+	fs.Var(a.engineURLs, "api-url", "Override an engine's API base URL as engine=URL (repeatable)")
+	fs.IntVar(&a.pages, "pages", 1, "Number of result pages to fetch from each engine (Shodan pages hold 100 results and cost 1 query credit each)")
+	fs.IntVar(&a.maxResults, "max-results", 0, "Stop after retrieving this many matches from each engine (0 = no limit)")
+	fs.IntVar(&a.creditFloor, "credit-floor", 0, "Never spend query credits below this balance")
+	fs.BoolVar(&a.resume, "resume", false, "Resume a previously interrupted Shodan pagination")
+	fs.BoolVar(&a.rawResults, "raw", false, "Keep each engine's raw JSON record with the normalised hosts")
+	fs.BoolVar(&a.countOnly, "count-only", false, "Only show Shodan match counts and facets (no query credits spent)")
+}
+
+// Build the finder configuration. Searching needs an API key for every
+// engine, taken from the flags, the environment or the config file.
+func (a *analysisFlags) config(hashOnly bool) (*Config, error) {
+	engines, err := parseEngineList(a.engineList)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := engineValues{}
+	for name, key := range a.engineKeys {
+		keys[name] = key
+	}
+	if a.shodanKey != "" && keys["shodan"] == "" {
+		keys["shodan"] = a.shodanKey
+	}
+	resolveEngineKeys(engines, keys)
+	if err := resolveConfigKeys(engines, keys); err != nil {
+		return nil, err
+	}
+
+	if !hashOnly {
+		var missing []string
+		for _, name := range engines {
+			if keys[name] == "" {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			warnColor.Println("[!] Tip: Use 'favhash hash' if you only want to calculate the hash.")
+			return nil, fmt.Errorf("API key required for %s. Use -k (Shodan), -key engine=KEY, %s or 'favhash config set key.%s KEY'",
+				strings.Join(missing, ", "), engineKeyEnv(missing[0]), missing[0])
+		}
+	}
+
+	// Silent mode replaces the chosen format with bare hashes
+	format := a.outputFormat
+	if a.silent {
+		format = "silent"
+	}
+
+	return &Config{
+		UserAgent:       a.customUA,
+		UserAgentFile:   a.uaFile,
+		UserAgentMode:   a.uaMode,
+		Timeout:         a.timeout,
+		RetryCount:      a.retryCount,
+		RetryDelay:      a.retryDelay,
+		ProxyURL:        a.proxyURL,
+		OutputFormat:    format,
+		FollowRedirect:  !a.noRedirect,
+		Debug:           a.debug,
+		SaveResults:     a.saveResults,
+		NoHistory:       a.noHistory,
+		HashProfile:     a.hashProfile,
+		ProbeVariants:   a.variants,
+		AllCandidates:   a.allIcons,
+		PathsFile:       a.pathsFile,
+		ReplacePaths:    a.replacePaths,
+		HostConcurrency: a.hostConns,
+		Pages:           a.pages,
+		MaxResults:      a.maxResults,
+		CreditFloor:     a.creditFloor,
+		Resume:          a.resume,
+		CountOnly:       a.countOnly,
+		Concurrency:     a.concurrency,
+		RateLimit:       a.rateLimit,
+		Engines:         engines,
+		EngineKeys:      keys,
+		EngineURLs:      a.engineURLs,
+		RawResults:      a.rawResults,
+		Fields:          a.fields,
+		ReportFile:      a.reportFile,
+		STIXFile:        a.stixFile,
+		FingerprintDBs:  a.fingerprintDBs,
+	}, nil
+}
+
+// Load the targets and analyze them. batch forces batch output even for
+// a single target.
+func (a *analysisFlags) runTargets(args []string, hashOnly, batch bool) error {
+	setupOutput(a.noColor, a.silent)
+
+	targets, err := loadTargets(args, a.listFile)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return errNoTargets
+	}
+
+	config, err := a.config(hashOnly)
+	if err != nil {
+		return err
+	}
+	config.BatchMode = batch || len(targets) > 1
+
+	// Print banner
+	if !a.silent {
+		fmt.Fprintf(infoColor.Writer(), banner, version)
+	}
+
+	finder, err := NewFaviconFinder(config)
+	if err != nil {
+		return err
+	}
+	return finder.run(targets, hashOnly)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumericArgsLast(t *testing.T) {
+	tests := []struct {
+		command string
+		args    string
+		flags   string
+		rest    string
+	}{
+		{"lookup", "-297069493", "", "-297069493"},
+		{"lookup", "-o json -297069493 81586312", "o=json", "-297069493 81586312"},
+		{"lookup", "-297069493 -o json", "o=json", "-297069493"},
+		{"lookup", "-engine=shodan -5", "engine=shodan", "-5"},
+		{"lookup", "-- -297069493", "", "-297069493"},
+		{"lookup", "81586312 abc -7", "", "81586312 abc -7"},
+		{"history", "-n 5 -484577076", "n=5", "-484577076"},
+		{"history", "-n -1 example.com", "n=-1", "example.com"},
+		{"history", "-failed -484577076", "failed=true", "-484577076"},
+		{"history", "example.com -failed", "", "example.com -failed"},
+		{"", "-hash -t 5s -3 example.com", "hash=true t=5s", "-3 example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command+" "+tt.args, func(t *testing.T) {
+			// No command is the legacy flag form
+			fs, _ := legacyFlags()
+			if tt.command != "" {
+				fs, _ = findCommand(tt.command).flags()
+			}
+			if err := fs.Parse(numericArgsLast(fs, strings.Fields(tt.args))); err != nil {
+				t.Fatal(err)
+			}
+
+			var set []string
+			for _, name := range strings.Fields(tt.flags) {
+				name, _, _ = strings.Cut(name, "=")
+				set = append(set, name+"="+fs.Lookup(name).Value.String())
+			}
+			if got := strings.Join(set, " "); got != tt.flags {
+				t.Errorf("flags = %q, want %q", got, tt.flags)
+			}
+			if got := strings.Join(fs.Args(), " "); got != tt.rest {
+				t.Errorf("args = %q, want %q", got, tt.rest)
+			}
+		})
+	}
+}
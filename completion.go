@@ -0,0 +1,284 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Flags whose values are file paths
+var completionFileFlags = map[string]bool{
+	"l": true, "paths": true, "ua-file": true, "report": true, "stix": true, "fingerprints": true,
+}
+
+// Positional arguments of commands that take a fixed set of words
+var completionArgs = map[string][]string{
+	"config":     {"path", "show", "set", "unset"},
+	"completion": {"bash", "zsh", "fish"},
+}
+
+// Commands whose positional arguments are local files
+var completionFileArgs = map[string]bool{"file": true}
+
+// completionFlag describes a flag for the completion scripts
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool
+	values []string
+}
+
+// Collect the flags of a flag set, adding the value lists known to favhash
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(fl *flag.Flag) {
+		cf := completionFlag{name: fl.Name, usage: fl.Usage}
+		if b, ok := fl.Value.(interface{ IsBoolFlag() bool }); ok {
+			cf.isBool = b.IsBoolFlag()
+		}
+		switch fl.Name {
+		case "engine":
+			cf.values = engineNames()
+		case "hash-profile":
+			cf.values = hashProfileNames()
+		case "ua-mode":
+			cf.values = []string{"random", "rotate", "host"}
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+// completionScope is the legacy form (empty name) or a command
+type completionScope struct {
+	name  string
+	flags []completionFlag
+}
+
+func completionScopes() []completionScope {
+	fs, _ := legacyFlags()
+	scopes := []completionScope{{flags: completionFlags(fs)}}
+	for _, cmd := range commands {
+		fs, _ := cmd.flags()
+		scopes = append(scopes, completionScope{name: cmd.name, flags: completionFlags(fs)})
+	}
+	return scopes
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands)+1)
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return append(names, "help")
+}
+
+func writeBashCompletion(w io.Writer) {
+	scopes := completionScopes()
+
+	fmt.Fprintf(w, "# bash completion for favhash\n\n")
+	fmt.Fprintf(w, "_favhash() {\n")
+	fmt.Fprintf(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "    local cmd=\"\" opts\n")
+	fmt.Fprintf(w, "    [[ $COMP_CWORD -gt 1 ]] && cmd=\"${COMP_WORDS[1]}\"\n\n")
+
+	// Flag values first, as they apply anywhere on the line
+	fmt.Fprintf(w, "    case \"$prev\" in\n")
+	values := make(map[string][]string)
+	for _, scope := range scopes {
+		for _, fl := range scope.flags {
+			if len(fl.values) > 0 {
+				values[fl.name] = fl.values
+			}
+		}
+	}
+	for _, name := range sortedKeys(values) {
+		fmt.Fprintf(w, "        -%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", name, strings.Join(values[name], " "))
+	}
+	fmt.Fprintf(w, "    esac\n\n")
+
+	fmt.Fprintf(w, "    case \"$cmd\" in\n")
+	for _, scope := range scopes[1:] {
+		fmt.Fprintf(w, "        %s)\n", scope.name)
+		if words, ok := completionArgs[scope.name]; ok {
+			fmt.Fprintf(w, "            if [[ $cur != -* ]]; then COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return; fi\n", strings.Join(words, " "))
+		}
+		fmt.Fprintf(w, "            opts=\"%s\" ;;\n", bashFlagList(scope.flags))
+	}
+	fmt.Fprintf(w, "        help) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(w, "        *)\n")
+	fmt.Fprintf(w, "            if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return; fi\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(w, "            opts=\"%s\" ;;\n", bashFlagList(scopes[0].flags))
+	fmt.Fprintf(w, "    esac\n\n")
+
+	fmt.Fprintf(w, "    if [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"$opts\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "complete -o default -F _favhash favhash\n")
+}
+
+func bashFlagList(flags []completionFlag) string {
+	names := make([]string, len(flags))
+	for i, fl := range flags {
+		names[i] = "-" + fl.name
+	}
+	return strings.Join(names, " ")
+}
+
+// Quote a description for a zsh _arguments spec
+func zshDescription(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "'", `'\''`).Replace(s)
+}
+
+func zshFlagSpec(fl completionFlag) string {
+	spec := "-" + fl.name + "[" + zshDescription(fl.usage) + "]"
+	switch {
+	case fl.isBool:
+	case fl.name == "engine":
+		spec += ":engines:_values -s , engine " + strings.Join(fl.values, " ")
+	case len(fl.values) > 0:
+		spec += ":value:(" + strings.Join(fl.values, " ") + ")"
+	case completionFileFlags[fl.name]:
+		spec += ":file:_files"
+	default:
+		spec += ":value: "
+	}
+	// Repeatable flags
+	switch fl.name {
+	case "key", "api-url", "fingerprints":
+		spec = "*" + spec
+	}
+	return "'" + spec + "'"
+}
+
+func writeZshCompletion(w io.Writer) {
+	scopes := completionScopes()
+
+	fmt.Fprintf(w, "#compdef favhash\n\n")
+	fmt.Fprintf(w, "_favhash() {\n")
+	fmt.Fprintf(w, "    local -a commands\n")
+	fmt.Fprintf(w, "    commands=(\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "        '%s:%s'\n", cmd.name, strings.ReplaceAll(zshDescription(cmd.summary), ":", `\:`))
+	}
+	fmt.Fprintf(w, "        'help:Show help for a command'\n")
+	fmt.Fprintf(w, "    )\n\n")
+
+	fmt.Fprintf(w, "    if (( CURRENT == 2 )) && [[ $words[CURRENT] != -* ]]; then\n")
+	fmt.Fprintf(w, "        _describe -t commands 'favhash command' commands\n")
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n\n")
+
+	fmt.Fprintf(w, "    local cmd=$words[2]\n")
+	fmt.Fprintf(w, "    if (( ${commands[(I)$cmd:*]} )) || [[ $cmd == help ]]; then\n")
+	fmt.Fprintf(w, "        shift words\n")
+	fmt.Fprintf(w, "        (( CURRENT-- ))\n")
+	fmt.Fprintf(w, "    else\n")
+	fmt.Fprintf(w, "        cmd=\n")
+	fmt.Fprintf(w, "    fi\n\n")
+
+	fmt.Fprintf(w, "    case $cmd in\n")
+	for _, scope := range scopes[1:] {
+		fmt.Fprintf(w, "        %s)\n", scope.name)
+		writeZshArguments(w, scope)
+	}
+	fmt.Fprintf(w, "        help)\n")
+	fmt.Fprintf(w, "            _arguments '1:command:(%s)' ;;\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(w, "        *)\n")
+	writeZshArguments(w, scopes[0])
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "}\n\n")
+
+	fmt.Fprintf(w, "if [[ $funcstack[1] == _favhash ]]; then\n")
+	fmt.Fprintf(w, "    _favhash \"$@\"\n")
+	fmt.Fprintf(w, "else\n")
+	fmt.Fprintf(w, "    compdef _favhash favhash\n")
+	fmt.Fprintf(w, "fi\n")
+}
+
+func writeZshArguments(w io.Writer, scope completionScope) {
+	fmt.Fprintf(w, "            _arguments \\\n")
+	for _, fl := range scope.flags {
+		fmt.Fprintf(w, "                %s \\\n", zshFlagSpec(fl))
+	}
+	switch {
+	case completionArgs[scope.name] != nil:
+		fmt.Fprintf(w, "                '1:argument:(%s)' \\\n", strings.Join(completionArgs[scope.name], " "))
+		fmt.Fprintf(w, "                '*:value: ' ;;\n")
+	case completionFileArgs[scope.name]:
+		fmt.Fprintf(w, "                '*:file:_files' ;;\n")
+	default:
+		fmt.Fprintf(w, "                '*:argument: ' ;;\n")
+	}
+}
+
+// Quote a string for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func writeFishCompletion(w io.Writer) {
+	scopes := completionScopes()
+
+	fmt.Fprintf(w, "# fish completion for favhash\n\n")
+	fmt.Fprintf(w, "complete -c favhash -f\n\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c favhash -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+	fmt.Fprintf(w, "complete -c favhash -n __fish_use_subcommand -a help -d 'Show help for a command'\n")
+	fmt.Fprintf(w, "complete -c favhash -n '__fish_seen_subcommand_from help' -a %s\n", fishQuote(strings.Join(commandNames(), " ")))
+
+	for _, scope := range scopes {
+		condition := "__fish_use_subcommand"
+		if scope.name != "" {
+			condition = fishQuote("__fish_seen_subcommand_from " + scope.name)
+			fmt.Fprintf(w, "\n# %s\n", scope.name)
+		} else {
+			fmt.Fprintf(w, "\n# favhash [options] <target> ...\n")
+		}
+
+		if words, ok := completionArgs[scope.name]; ok {
+			fmt.Fprintf(w, "complete -c favhash -n %s -a %s\n", condition, fishQuote(strings.Join(words, " ")))
+		}
+		if completionFileArgs[scope.name] {
+			fmt.Fprintf(w, "complete -c favhash -n %s -F\n", condition)
+		}
+
+		for _, fl := range scope.flags {
+			line := fmt.Sprintf("complete -c favhash -n %s -o %s", condition, fl.name)
+			switch {
+			case fl.isBool:
+			case len(fl.values) > 0:
+				line += " -x -a " + fishQuote(strings.Join(fl.values, " "))
+			case completionFileFlags[fl.name]:
+				line += " -r -F"
+			default:
+				line += " -x"
+			}
+			fmt.Fprintf(w, "%s -d %s\n", line, fishQuote(fl.usage))
+		}
+	}
+}
+
+// Flags of the completion subcommand
+func completionCommand(flags *flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if len(args) != 1 {
+			return usageError("no shell given")
+		}
+		switch args[0] {
+		case "bash":
+			writeBashCompletion(os.Stdout)
+		case "zsh":
+			writeZshCompletion(os.Stdout)
+		case "fish":
+			writeFishCompletion(os.Stdout)
+		default:
+			return fmt.Errorf("unsupported shell %q (use bash, zsh or fish)", args[0])
+		}
+		return nil
+	}
+}
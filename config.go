@@ -0,0 +1,275 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variable overriding the config file location
+const configEnv = "FAVHASH_CONFIG"
+
+// Flags that cannot be given a saved default: keys are stored separately
+// and the rest only make sense on the command line
+var configExcludedFlags = map[string]bool{"k": true, "key": true, "h": true}
+
+// fileConfig is the saved configuration: flag defaults by flag name and
+// API keys by engine
+type fileConfig struct {
+	Defaults map[string]string `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	Keys     map[string]string `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+// Location of the config file: $FAVHASH_CONFIG, or favhash/config.yaml
+// in the user config directory
+func configPath() (string, error) {
+	if path := os.Getenv(configEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate config directory: %v", err)
+	}
+	return filepath.Join(dir, "favhash", "config.yaml"), nil
+}
+
+// Load the config file. A missing file is an empty config.
+func loadFileConfig() (*fileConfig, error) {
+	cfg := &fileConfig{}
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Write the config file, readable only by the user as it holds API keys
+func (c *fileConfig) save() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("error creating config directory: %v", err)
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("error writing config file: %v", err)
+	}
+	return path, nil
+}
+
+// Apply saved defaults to the flags of fs that were not given on the
+// command line. Defaults for flags the command does not have are ignored.
+func applyConfigDefaults(fs *flag.FlagSet) error {
+	cfg, err := loadFileConfig()
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) {
+		given[fl.Name] = true
+	})
+
+	for name, value := range cfg.Defaults {
+		if given[name] || configExcludedFlags[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("config file default %s=%q: %v", name, value, err)
+		}
+	}
+	return nil
+}
+
+// Fill in API keys still missing after the flags and environment from
+// the config file
+func resolveConfigKeys(names []string, keys map[string]string) error {
+	cfg, err := loadFileConfig()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if keys[name] == "" {
+			keys[name] = cfg.Keys[name]
+		}
+	}
+	return nil
+}
+
+// Find a flag by name across the legacy form and every command, returning
+// a fresh flag set holding it so values can be checked
+func lookupConfigFlag(name string) (*flag.FlagSet, *flag.Flag) {
+	fs, _ := legacyFlags()
+	if fl := fs.Lookup(name); fl != nil {
+		return fs, fl
+	}
+	for _, cmd := range commands {
+		fs, _ := cmd.flags()
+		if fl := fs.Lookup(name); fl != nil {
+			return fs, fl
+		}
+	}
+	return nil, nil
+}
+
+// Check a config setting, returning the engine for key.<engine> names
+func checkConfigSetting(name, value string, setting bool) (string, error) {
+	if engine, ok := strings.CutPrefix(name, "key."); ok {
+		if _, known := searchEngines[engine]; !known {
+			return "", fmt.Errorf("unknown search engine %q (available: %s)", engine, strings.Join(engineNames(), ", "))
+		}
+		return engine, nil
+	}
+
+	fs, fl := lookupConfigFlag(name)
+	if fl == nil || configExcludedFlags[name] {
+		return "", fmt.Errorf("unknown setting %q (use a flag name such as engine or proxy, or key.<engine>)", name)
+	}
+	if setting {
+		if err := fs.Set(name, value); err != nil {
+			return "", fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+	}
+	return "", nil
+}
+
+// Hide all but the start of an API key
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", 8)
+}
+
+// Flags of the config subcommand
+func configCommand(flags *flag.FlagSet) func(args []string) error {
+	reveal := flags.Bool("reveal", false, "Show API keys in full")
+	noColor := flags.Bool("no-color", false, "Disable colored output")
+
+	return func(args []string) error {
+		setupOutput(*noColor, false)
+
+		action := "show"
+		if len(args) > 0 {
+			action, args = args[0], args[1:]
+		}
+
+		switch action {
+		case "path":
+			path, err := configPath()
+			if err != nil {
+				return err
+			}
+			resultColor.Println(path)
+			return nil
+
+		case "show":
+			return showConfig(*reveal)
+
+		case "set":
+			if len(args) != 2 {
+				return fmt.Errorf("usage: favhash config set <name> <value>")
+			}
+			return changeConfig(args[0], args[1], true)
+
+		case "unset":
+			if len(args) != 1 {
+				return fmt.Errorf("usage: favhash config unset <name>")
+			}
+			return changeConfig(args[0], "", false)
+		}
+		return fmt.Errorf("unknown config action %q (use path, show, set or unset)", action)
+	}
+}
+
+func showConfig(reveal bool) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadFileConfig()
+	if err != nil {
+		return err
+	}
+
+	infoColor.Printf("[*] Config file: %s\n", path)
+	if len(cfg.Defaults) == 0 && len(cfg.Keys) == 0 {
+		warnColor.Println("[!] No settings saved")
+		return nil
+	}
+
+	for _, name := range sortedKeys(cfg.Defaults) {
+		resultColor.Printf("%s = %s\n", name, cfg.Defaults[name])
+	}
+	for _, engine := range sortedKeys(cfg.Keys) {
+		key := cfg.Keys[engine]
+		if !reveal {
+			key = maskKey(key)
+		}
+		resultColor.Printf("key.%s = %s\n", engine, key)
+	}
+	return nil
+}
+
+// Set or remove a default or API key and save the file
+func changeConfig(name, value string, setting bool) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	engine, err := checkConfigSetting(name, value, setting)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadFileConfig()
+	if err != nil {
+		return err
+	}
+
+	values, entry := &cfg.Defaults, name
+	if engine != "" {
+		values, entry = &cfg.Keys, engine
+	}
+	if *values == nil {
+		*values = make(map[string]string)
+	}
+
+	if setting {
+		(*values)[entry] = value
+	} else {
+		if _, ok := (*values)[entry]; !ok {
+			return fmt.Errorf("%s is not set", name)
+		}
+		delete(*values, entry)
+	}
+
+	path, err := cfg.save()
+	if err != nil {
+		return err
+	}
+	if setting {
+		successColor.Printf("[+] Saved %s to %s\n", name, path)
+	} else {
+		successColor.Printf("[+] Removed %s from %s\n", name, path)
+	}
+	return nil
+}
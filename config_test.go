@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(configEnv, path)
+
+	saved := &fileConfig{
+		Defaults: map[string]string{"engine": "fofa", "t": "30s", "k": "file-key", "proxy": "http://127.0.0.1:3128"},
+		Keys:     map[string]string{"fofa": "fofa-file-key", "shodan": "shodan-file-key"},
+	}
+	if _, err := saved.save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "defaults:") || !strings.Contains(string(data), "keys:") {
+		t.Errorf("config file does not use the documented keys:\n%s", data)
+	}
+
+	// The command has no proxy flag, so that default is ignored
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	engine := fs.String("engine", "shodan", "")
+	timeout := fs.Duration("t", 10*time.Second, "")
+	key := fs.String("k", "", "")
+	if err := fs.Parse([]string{"-t", "5s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigDefaults(fs); err != nil {
+		t.Fatal(err)
+	}
+	if *engine != "fofa" {
+		t.Errorf("engine = %s, want the config file default", *engine)
+	}
+	if *timeout != 5*time.Second {
+		t.Errorf("t = %v, want the flag to win over the config file", *timeout)
+	}
+	if *key != "" {
+		t.Errorf("k = %q, want API keys kept out of flag defaults", *key)
+	}
+
+	// Keys from flags and the environment win over the config file
+	keys := map[string]string{"shodan": "flag-key"}
+	if err := resolveConfigKeys([]string{"shodan", "fofa", "netlas"}, keys); err != nil {
+		t.Fatal(err)
+	}
+	if keys["shodan"] != "flag-key" || keys["fofa"] != "fofa-file-key" || keys["netlas"] != "" {
+		t.Errorf("keys = %v", keys)
+	}
+}
+
+func TestConfigDefaultsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv(configEnv, path)
+	if err := os.WriteFile(path, []byte("defaults:\n  t: soon\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Duration("t", 10*time.Second, "")
+	if err := applyConfigDefaults(fs); err == nil || !strings.Contains(err.Error(), "t=\"soon\"") {
+		t.Errorf("applyConfigDefaults() error = %v, want the bad default named", err)
+	}
+}
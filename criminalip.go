@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const criminalIPPageSize = 10
+
+// criminalIPEngine searches Criminal IP banners by MMH3 hash
+type criminalIPEngine struct {
+	engineBase
+}
+
+type criminalIPUser struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		AccountType string `json:"account_type"`
+	} `json:"data"`
+}
+
+type criminalIPBanner struct {
+	IPAddress      string `json:"ip_address"`
+	Port           int    `json:"open_port_no"`
+	Domain         string `json:"domain"`
+	Hostname       string `json:"hostname"`
+	ASName         string `json:"as_name"`
+	OrgName        string `json:"org_name"`
+	Country        string `json:"country"`
+	City           string `json:"city"`
+	Product        string `json:"product"`
+	ProductVersion string `json:"product_version"`
+	Title          string `json:"title"`
+	ScanTime       string `json:"scan_dtime"`
+}
+
+type criminalIPSearchResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		Count  int               `json:"count"`
+		Result []json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (e *criminalIPEngine) request(method, path string, params url.Values) (*http.Request, error) {
+	reqURL := e.baseURL + path
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(method, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", e.key)
+	return req, nil
+}
+
+func (e *criminalIPEngine) user() (*criminalIPUser, error) {
+	req, err := e.request("POST", "/v1/user/me", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user criminalIPUser
+	if err := e.f.fetchJSON(req, e.name, &user); err != nil {
+		return nil, err
+	}
+	if user.Status != 200 {
+		return nil, fmt.Errorf("Criminal IP API error (status %d): %s", user.Status, user.Message)
+	}
+	return &user, nil
+}
+
+func (e *criminalIPEngine) ValidateKey() error {
+	_, err := e.user()
+	return err
+}
+
+func (e *criminalIPEngine) Quota() (*EngineQuota, error) {
+	user, err := e.user()
+	if err != nil {
+		return nil, err
+	}
+	return &EngineQuota{Plan: user.Data.AccountType}, nil
+}
+
+func (e *criminalIPEngine) Query(fp *Fingerprint) string {
+	return fmt.Sprintf("favicon: %d", fp.MMH3)
+}
+
+func (e *criminalIPEngine) ManualSearchURL(fp *Fingerprint) string {
+	return "https://www.criminalip.io/asset/search?query=" + url.QueryEscape(e.Query(fp))
+}
+
+func (e *criminalIPEngine) SearchHash(fp *Fingerprint) (*SearchResult, error) {
+	query := e.Query(fp)
+
+	return e.f.collectPages(criminalIPPageSize, func(page int) ([]Host, int, error) {
+		params := url.Values{"query": {query}, "offset": {strconv.Itoa((page - 1) * criminalIPPageSize)}}
+		req, err := e.request("GET", "/v1/banner/search", params)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var resp criminalIPSearchResponse
+		if err := e.f.fetchJSON(req, e.name, &resp); err != nil {
+			return nil, 0, err
+		}
+		if resp.Status != 200 {
+			return nil, 0, fmt.Errorf("Criminal IP API error (status %d): %s", resp.Status, resp.Message)
+		}
+
+		var hosts []Host
+		for _, raw := range resp.Data.Result {
+			var item criminalIPBanner
+			if err := json.Unmarshal(raw, &item); err != nil {
+				e.f.debug("Skipping undecodable Criminal IP banner: %v", err)
+				continue
+			}
+
+			host := Host{
+				IP:         item.IPAddress,
+				Port:       item.Port,
+				Org:        item.OrgName,
+				ISP:        item.ASName,
+				Country:    item.Country,
+				City:       item.City,
+				Product:    item.Product,
+				Version:    item.ProductVersion,
+				HTTPTitle:  item.Title,
+				LastUpdate: item.ScanTime,
+			}
+			if item.Hostname != "" {
+				host.Hostnames = []string{item.Hostname}
+			}
+			if item.Domain != "" {
+				host.Domains = []string{item.Domain}
+			}
+			e.f.attachRaw(&host, e.name, raw)
+			hosts = append(hosts, host)
+		}
+		return hosts, resp.Data.Count, nil
+	})
+}
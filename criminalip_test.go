@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCriminalIPSearch(t *testing.T) {
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "key" || r.URL.Query().Get("query") != "favicon: -1" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		offset := r.URL.Query().Get("offset")
+		offsets = append(offsets, offset)
+		first, _ := strconv.Atoi(offset)
+
+		var banners []string
+		for i := 0; i < criminalIPPageSize; i++ {
+			banners = append(banners, fmt.Sprintf(`{
+				"ip_address": "192.0.2.%d", "open_port_no": 8080, "domain": "example.com",
+				"hostname": "app.example.com", "as_name": "Example ASN", "org_name": "Example Ltd",
+				"country": "KR", "city": "Seoul", "product": "Tomcat", "product_version": "9.0",
+				"title": "Manager", "scan_dtime": "2024-05-01 00:00:00"
+			}`, first+i))
+		}
+		fmt.Fprintf(w, `{"status": 200, "message": "api success", "data": {"count": %d, "result": [%s]}}`,
+			2*criminalIPPageSize, strings.Join(banners, ","))
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"criminalip"},
+		EngineKeys: map[string]string{"criminalip": "key"},
+		EngineURLs: map[string]string{"criminalip": server.URL},
+		Pages:      5,
+	})
+
+	result, err := f.engines[0].SearchHash(&Fingerprint{MMH3: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Paging stops once the reported count is reached
+	if strings.Join(offsets, ",") != fmt.Sprintf("0,%d", criminalIPPageSize) {
+		t.Errorf("fetched offsets %v, want 0 and %d", offsets, criminalIPPageSize)
+	}
+	if result.Total != 2*criminalIPPageSize || len(result.Hosts) != 2*criminalIPPageSize || !result.Complete {
+		t.Errorf("total %d, %d hosts, complete %v", result.Total, len(result.Hosts), result.Complete)
+	}
+
+	host := result.Hosts[0]
+	if host.Port != 8080 || host.Org != "Example Ltd" || host.ISP != "Example ASN" || host.City != "Seoul" {
+		t.Errorf("host = %+v", host)
+	}
+	if host.Product != "Tomcat" || host.Version != "9.0" || host.HTTPTitle != "Manager" {
+		t.Errorf("service = %q, %q, %q", host.Product, host.Version, host.HTTPTitle)
+	}
+	if len(host.Hostnames) != 1 || host.Hostnames[0] != "app.example.com" || len(host.Domains) != 1 {
+		t.Errorf("hostnames = %v, domains = %v", host.Hostnames, host.Domains)
+	}
+}
+
+func TestCriminalIPQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/user/me" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		fmt.Fprint(w, `{"status": 200, "message": "api success", "data": {"account_type": "pro"}}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"criminalip"},
+		EngineKeys: map[string]string{"criminalip": "key"},
+		EngineURLs: map[string]string{"criminalip": server.URL},
+	})
+
+	quota, err := f.engines[0].Quota()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Plan != "pro" {
+		t.Errorf("quota = %+v, want the pro plan", quota)
+	}
+}
+
+func TestCriminalIPAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		// Criminal IP can report an error in the body of a 200 response
+		{"error field", http.StatusOK, `{"status": 401, "message": "invalid api key"}`, "invalid api key"},
+		{"error status", http.StatusForbidden, `{"status": 403}`, "status 403"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			f := newTestFinder(t, &Config{
+				Engines:    []string{"criminalip"},
+				EngineKeys: map[string]string{"criminalip": "key"},
+				EngineURLs: map[string]string{"criminalip": server.URL},
+			})
+
+			if _, err := f.engines[0].SearchHash(&Fingerprint{MMH3: 1}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("SearchHash() error = %v, want %q", err, tt.want)
+			}
+			if _, err := f.engines[0].Quota(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Quota() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
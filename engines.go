@@ -0,0 +1,520 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+const defaultEngine = "shodan"
+
+// SearchEngine is an internet search engine that can look up hosts serving
+// a favicon. Each engine queries its own hash flavour (MMH3, MD5 or SHA256).
+type SearchEngine interface {
+	Name() string
+	ValidateKey() error
+	Quota() (*EngineQuota, error)
+	Query(fp *Fingerprint) string
+	SearchHash(fp *Fingerprint) (*SearchResult, error)
+	ManualSearchURL(fp *Fingerprint) string
+}
+
+// Counter is implemented by engines that can count matches without
+// spending query credits
+type Counter interface {
+	Count(fp *Fingerprint) (*HostCount, error)
+}
+
+// EngineQuota is the remaining API allowance of an engine. Unit is empty
+// when the engine only reports the plan.
+type EngineQuota struct {
+	Plan      string `json:"plan,omitempty"`
+	Remaining int    `json:"remaining"`
+	Unit      string `json:"unit,omitempty"`
+}
+
+type FacetValue struct {
+	Count int         `json:"count"`
+	Value interface{} `json:"value"`
+}
+
+// HostCount is a free match count, optionally broken down by facet
+type HostCount struct {
+	Total  int                     `json:"total"`
+	Facets map[string][]FacetValue `json:"facets,omitempty"`
+}
+
+// Host is a search engine match, normalised across engines. Raw keeps each
+// engine's original record (with -raw) for lossless re-processing.
+type Host struct {
+	IP             string                     `json:"ip"`
+	Port           int                        `json:"port,omitempty"`
+	Transport      string                     `json:"transport,omitempty"`
+	Hostnames      []string                   `json:"hostnames,omitempty"`
+	Domains        []string                   `json:"domains,omitempty"`
+	Org            string                     `json:"org,omitempty"`
+	ASN            string                     `json:"asn,omitempty"`
+	ISP            string                     `json:"isp,omitempty"`
+	Country        string                     `json:"country,omitempty"`
+	City           string                     `json:"city,omitempty"`
+	Product        string                     `json:"product,omitempty"`
+	Version        string                     `json:"version,omitempty"`
+	HTTPTitle      string                     `json:"http_title,omitempty"`
+	HTTPServer     string                     `json:"http_server,omitempty"`
+	SSLSubject     string                     `json:"ssl_subject,omitempty"`
+	SSLIssuer      string                     `json:"ssl_issuer,omitempty"`
+	SSLFingerprint string                     `json:"ssl_fingerprint,omitempty"`
+	Vulns          []string                   `json:"vulns,omitempty"`
+	BannerHash     int64                      `json:"banner_hash,omitempty"`
+	LastUpdate     string                     `json:"last_update,omitempty"`
+	Tags           []string                   `json:"tags,omitempty"`
+	Engines        []string                   `json:"engines"`
+	Raw            map[string]json.RawMessage `json:"raw,omitempty"`
+}
+
+// SearchResult is the outcome of searching one engine for a fingerprint
+type SearchResult struct {
+	Engine       string     `json:"engine"`
+	Query        string     `json:"query"`
+	SearchURL    string     `json:"search_url"`
+	Count        *HostCount `json:"count,omitempty"`
+	Total        int        `json:"total"`
+	Retrieved    int        `json:"retrieved"`
+	Pages        int        `json:"pages,omitempty"`
+	CreditsSpent int        `json:"credits_spent,omitempty"`
+	Complete     bool       `json:"complete,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	Hosts        []Host     `json:"-"`
+}
+
+// Returned by SearchHash when searching would spend credits below the floor
+var errNoCredits = errors.New("no query credits available")
+
+// engineBase holds what every engine needs to talk to its API
+type engineBase struct {
+	f       *FaviconFinder
+	name    string
+	key     string
+	baseURL string
+}
+
+func (e *engineBase) Name() string {
+	return e.name
+}
+
+// engineSpec describes a supported engine. hash names the fingerprint hash
+// its query uses (mmh3, md5 or sha256).
+type engineSpec struct {
+	title   string
+	baseURL string
+	hash    string
+	create  func(base engineBase) SearchEngine
+}
+
+// Supported engines and their default API endpoints
+var searchEngines = map[string]engineSpec{
+	"shodan":     {"Shodan", "https://api.shodan.io", "mmh3", func(b engineBase) SearchEngine { return &shodanEngine{engineBase: b} }},
+	"censys":     {"Censys", "https://search.censys.io/api", "md5", func(b engineBase) SearchEngine { return &censysEngine{b} }},
+	"fofa":       {"FOFA", "https://fofa.info", "mmh3", func(b engineBase) SearchEngine { return &fofaEngine{b} }},
+	"zoomeye":    {"ZoomEye", "https://api.zoomeye.org", "mmh3", func(b engineBase) SearchEngine { return &zoomeyeEngine{b} }},
+	"hunter":     {"Hunter.how", "https://api.hunter.how", "md5", func(b engineBase) SearchEngine { return &hunterEngine{b} }},
+	"netlas":     {"Netlas", "https://app.netlas.io", "sha256", func(b engineBase) SearchEngine { return &netlasEngine{b} }},
+	"criminalip": {"Criminal IP", "https://api.criminalip.io", "mmh3", func(b engineBase) SearchEngine { return &criminalIPEngine{b} }},
+}
+
+func engineNames() []string {
+	names := make([]string, 0, len(searchEngines))
+	for name := range searchEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Display name of an engine
+func engineTitle(name string) string {
+	if spec, ok := searchEngines[name]; ok {
+		return spec.title
+	}
+	return name
+}
+
+// Parse a comma-separated -engine list
+func parseEngineList(list string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := searchEngines[name]; !ok {
+			return nil, fmt.Errorf("unknown search engine %q (available: %s)", name, strings.Join(engineNames(), ", "))
+		}
+		names = appendUnique(names, name)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no search engine selected")
+	}
+	return names, nil
+}
+
+// Environment variable holding an engine's API key, e.g. CENSYS_API_KEY
+func engineKeyEnv(name string) string {
+	return strings.ToUpper(name) + "_API_KEY"
+}
+
+// Fill in API keys missing from the flags from the environment
+func resolveEngineKeys(names []string, keys map[string]string) {
+	for _, name := range names {
+		if keys[name] == "" {
+			keys[name] = os.Getenv(engineKeyEnv(name))
+		}
+	}
+}
+
+// engineValues collects repeatable engine=value flags such as -key
+type engineValues map[string]string
+
+func (v engineValues) String() string {
+	var pairs []string
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v engineValues) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !ok || value == "" {
+		return fmt.Errorf("expected engine=value, got %q", s)
+	}
+	if _, ok := searchEngines[name]; !ok {
+		return fmt.Errorf("unknown search engine %q", name)
+	}
+	v[name] = value
+	return nil
+}
+
+// Create the engines selected in the config
+func newSearchEngines(f *FaviconFinder, config *Config) ([]SearchEngine, error) {
+	names := config.Engines
+	if len(names) == 0 {
+		names = []string{defaultEngine}
+	}
+
+	var engines []SearchEngine
+	for _, name := range names {
+		spec, ok := searchEngines[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown search engine %q", name)
+		}
+
+		baseURL := spec.baseURL
+		if custom := config.EngineURLs[name]; custom != "" {
+			baseURL = custom
+		}
+
+		engines = append(engines, spec.create(engineBase{
+			f:       f,
+			name:    name,
+			key:     config.EngineKeys[name],
+			baseURL: strings.TrimSuffix(baseURL, "/"),
+		}))
+	}
+	return engines, nil
+}
+
+// Send an API request and decode its JSON response into v
+func (f *FaviconFinder) fetchJSON(req *http.Request, engine string, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.do(req, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s API error (status %d): %s", engineTitle(engine), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s response: %v", engineTitle(engine), err)
+	}
+	return nil
+}
+
+// Fetch result pages until -pages or -max-results is reached or the results
+// run out. fetch returns the hosts on a page and the total match count, or
+// a negative total when the engine does not report one.
+func (f *FaviconFinder) collectPages(pageSize int, fetch func(page int) ([]Host, int, error)) (*SearchResult, error) {
+	pages := f.config.Pages
+	if pages <= 0 {
+		pages = 1
+	}
+	maxResults := f.config.MaxResults
+
+	result := &SearchResult{}
+	for page := 1; page <= pages; page++ {
+		if maxResults > 0 && len(result.Hosts) >= maxResults {
+			break
+		}
+
+		f.debug("Fetching result page %d", page)
+		hosts, total, err := fetch(page)
+		if err != nil {
+			if len(result.Hosts) == 0 {
+				return nil, err
+			}
+			warnColor.Printf("\n[!] Search stopped early: %v\n", err)
+			break
+		}
+
+		result.Hosts = append(result.Hosts, hosts...)
+		result.Total = total
+		result.Pages = page
+
+		if len(hosts) < pageSize || (total >= 0 && page*pageSize >= total) {
+			result.Complete = true
+			break
+		}
+	}
+
+	if result.Total < 0 {
+		result.Total = len(result.Hosts)
+	}
+	if maxResults > 0 && len(result.Hosts) >= maxResults {
+		result.Hosts = result.Hosts[:maxResults]
+		result.Complete = true
+	}
+	return result, nil
+}
+
+// Count and search one engine. Failures are recorded in the returned result
+// so the remaining engines still run.
+func (f *FaviconFinder) searchEngine(engine SearchEngine, fp *Fingerprint) *SearchResult {
+	title := engineTitle(engine.Name())
+	searchURL := engine.ManualSearchURL(fp)
+
+	var count *HostCount
+	if counter, ok := engine.(Counter); ok {
+		// Free triage before spending query credits
+		infoColor.Printf("[*] Counting %s matches...\n", title)
+		var err error
+		if count, err = counter.Count(fp); err != nil {
+			warnColor.Printf("\n[!] %s count failed: %v\n", title, err)
+		} else {
+			successColor.Printf("[+] %s reports %d matches\n", title, count.Total)
+		}
+	}
+
+	search := &SearchResult{}
+	switch {
+	case f.config.CountOnly:
+		infoColor.Printf("[*] %s search URL: %s\n", title, searchURL)
+	case count != nil && count.Total == 0:
+		infoColor.Printf("[*] No %s matches; skipping search to save query credits\n", title)
+	default:
+		infoColor.Printf("[*] Searching %s...\n", title)
+		found, err := engine.SearchHash(fp)
+		switch {
+		case err == errNoCredits:
+			warnColor.Printf("\n[!] No %s query credits available\n", title)
+			warnColor.Printf("[!] %s search URL: %s\n", title, searchURL)
+		case err != nil:
+			warnColor.Printf("\n[!] %s search failed: %v\n", title, err)
+			warnColor.Printf("[!] Try searching manually: %s\n", searchURL)
+			search.Error = err.Error()
+		default:
+			search = found
+			search.Retrieved = len(found.Hosts)
+			successColor.Printf("[+] %s found %d matches (retrieved %d)\n", title, search.Total, search.Retrieved)
+		}
+	}
+
+	search.Engine = engine.Name()
+	search.Query = engine.Query(fp)
+	search.SearchURL = searchURL
+	search.Count = count
+	if count != nil && count.Total > search.Total {
+		search.Total = count.Total
+	}
+	for i := range search.Hosts {
+		search.Hosts[i].Engines = []string{engine.Name()}
+	}
+	return search
+}
+
+// Merge the hosts found by every engine, keyed by IP and port
+func mergeHosts(searches []*SearchResult) []Host {
+	var hosts []Host
+	index := make(map[string]int)
+
+	for _, search := range searches {
+		for _, host := range search.Hosts {
+			key := fmt.Sprintf("%s:%d", host.IP, host.Port)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(hosts)
+				hosts = append(hosts, host)
+				continue
+			}
+
+			mergeHost(&hosts[i], &host)
+		}
+	}
+	return hosts
+}
+
+// Merge another engine's record of the same service into host. Lists are
+// combined; single values are only filled in where host has none.
+func mergeHost(host, other *Host) {
+	for _, list := range []struct {
+		into *[]string
+		from []string
+	}{
+		{&host.Hostnames, other.Hostnames},
+		{&host.Domains, other.Domains},
+		{&host.Vulns, other.Vulns},
+		{&host.Tags, other.Tags},
+		{&host.Engines, other.Engines},
+	} {
+		for _, value := range list.from {
+			*list.into = appendUnique(*list.into, value)
+		}
+	}
+
+	for _, field := range []struct {
+		into *string
+		from string
+	}{
+		{&host.Transport, other.Transport},
+		{&host.Org, other.Org},
+		{&host.ASN, other.ASN},
+		{&host.ISP, other.ISP},
+		{&host.Country, other.Country},
+		{&host.City, other.City},
+		{&host.Product, other.Product},
+		{&host.Version, other.Version},
+		{&host.HTTPTitle, other.HTTPTitle},
+		{&host.HTTPServer, other.HTTPServer},
+		{&host.SSLSubject, other.SSLSubject},
+		{&host.SSLIssuer, other.SSLIssuer},
+		{&host.SSLFingerprint, other.SSLFingerprint},
+		{&host.LastUpdate, other.LastUpdate},
+	} {
+		if *field.into == "" {
+			*field.into = field.from
+		}
+	}
+
+	if host.BannerHash == 0 {
+		host.BannerHash = other.BannerHash
+	}
+	for engine, raw := range other.Raw {
+		if host.Raw == nil {
+			host.Raw = make(map[string]json.RawMessage)
+		}
+		host.Raw[engine] = raw
+	}
+}
+
+// Keep an engine's original record on the host when -raw is set
+func (f *FaviconFinder) attachRaw(host *Host, engine string, raw json.RawMessage) {
+	if f.config.RawResults {
+		host.Raw = map[string]json.RawMessage{engine: raw}
+	}
+}
+
+// Format an AS number that engines report as a number, string or list
+func formatASN(asn interface{}) string {
+	switch v := asn.(type) {
+	case nil:
+		return ""
+	case float64:
+		if v == 0 {
+			return ""
+		}
+		return fmt.Sprintf("AS%d", int64(v))
+	case string:
+		if v == "" || strings.HasPrefix(strings.ToUpper(v), "AS") {
+			return strings.ToUpper(v)
+		}
+		return "AS" + v
+	case []interface{}:
+		if len(v) > 0 {
+			return formatASN(v[0])
+		}
+		return ""
+	}
+	return fmt.Sprint(asn)
+}
+
+// Format a certificate name as "CN=..., O=...", most significant parts first
+func formatDN(name map[string]string) string {
+	order := []string{"CN", "O", "OU", "L", "ST", "C"}
+	seen := make(map[string]bool)
+
+	var parts []string
+	for _, key := range order {
+		if value := name[key]; value != "" {
+			parts = append(parts, key+"="+value)
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key, value := range name {
+		if !seen[key] && value != "" {
+			rest = append(rest, key+"="+value)
+		}
+	}
+	sort.Strings(rest)
+	return strings.Join(append(parts, rest...), ", ")
+}
+
+// First non-empty string of a value engines report as a string or a list
+// of strings
+func firstString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// Sorted keys of a map, e.g. the CVE IDs of a vulns object
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Join the non-empty values with sep
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in fingerprint database
+//
+//go:embed fingerprints.yaml
+var builtinFingerprints []byte
+
+// KnownFavicon is a fingerprint database entry mapping favicon hashes to
+// a product
+type KnownFavicon struct {
+	Product    string   `json:"product" yaml:"product"`
+	Vendor     string   `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	Category   string   `json:"category,omitempty" yaml:"category,omitempty"`
+	References []string `json:"references,omitempty" yaml:"references,omitempty"`
+	MMH3       []int32  `json:"mmh3,omitempty" yaml:"mmh3,omitempty"`
+	MD5        []string `json:"md5,omitempty" yaml:"md5,omitempty"`
+	SHA256     []string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+}
+
+// ProductMatch is a product identified from a favicon hash
+type ProductMatch struct {
+	Product    string   `json:"product"`
+	Vendor     string   `json:"vendor,omitempty"`
+	Category   string   `json:"category,omitempty"`
+	References []string `json:"references,omitempty"`
+	MatchedOn  string   `json:"matched_on"`
+	Source     string   `json:"source"`
+}
+
+// fingerprintDB indexes known favicons by each of their hashes
+type fingerprintDB struct {
+	entries  []KnownFavicon
+	sources  []string
+	byMMH3   map[int32][]int
+	byMD5    map[string][]int
+	bySHA256 map[string][]int
+}
+
+// Hash profiles whose MMH3 values are comparable with the database, which
+// stores Shodan-encoded hashes
+var fingerprintMMH3Profiles = map[string]bool{"shodan": true, "fofa": true}
+
+// Load the built-in database followed by any user files
+func loadFingerprintDB(files []string) (*fingerprintDB, error) {
+	db := &fingerprintDB{
+		byMMH3:   make(map[int32][]int),
+		byMD5:    make(map[string][]int),
+		bySHA256: make(map[string][]int),
+	}
+
+	if err := db.load(builtinFingerprints, "builtin", false); err != nil {
+		return nil, fmt.Errorf("built-in fingerprints: %v", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fingerprints file: %v", err)
+		}
+		isJSON := strings.EqualFold(filepath.Ext(file), ".json")
+		if err := db.load(data, file, isJSON); err != nil {
+			return nil, fmt.Errorf("fingerprints file %s: %v", file, err)
+		}
+	}
+
+	return db, nil
+}
+
+// Parse a list of entries and add them to the index
+func (db *fingerprintDB) load(data []byte, source string, isJSON bool) error {
+	var entries []KnownFavicon
+	var err error
+	if isJSON {
+		err = json.Unmarshal(data, &entries)
+	} else {
+		err = yaml.Unmarshal(data, &entries)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		if strings.TrimSpace(entry.Product) == "" {
+			return fmt.Errorf("entry %d: missing product", i+1)
+		}
+		if len(entry.MMH3)+len(entry.MD5)+len(entry.SHA256) == 0 {
+			return fmt.Errorf("entry %d (%s): no hashes", i+1, entry.Product)
+		}
+
+		index := len(db.entries)
+		db.entries = append(db.entries, entry)
+		db.sources = append(db.sources, source)
+		for _, hash := range entry.MMH3 {
+			db.byMMH3[hash] = append(db.byMMH3[hash], index)
+		}
+		for _, hash := range entry.MD5 {
+			key := strings.ToLower(strings.TrimSpace(hash))
+			db.byMD5[key] = append(db.byMD5[key], index)
+		}
+		for _, hash := range entry.SHA256 {
+			key := strings.ToLower(strings.TrimSpace(hash))
+			db.bySHA256[key] = append(db.bySHA256[key], index)
+		}
+	}
+	return nil
+}
+
+func (db *fingerprintDB) size() int {
+	return len(db.entries)
+}
+
+// Find the products matching a fingerprint. Each entry is reported once,
+// on its strongest hash: SHA-256, then MD5, then MMH3.
+func (db *fingerprintDB) lookup(fp *Fingerprint) []ProductMatch {
+	if db == nil || fp == nil {
+		return nil
+	}
+
+	var matches []ProductMatch
+	seen := make(map[int]bool)
+	add := func(indexes []int, hash string) {
+		for _, i := range indexes {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			entry := db.entries[i]
+			matches = append(matches, ProductMatch{
+				Product:    entry.Product,
+				Vendor:     entry.Vendor,
+				Category:   entry.Category,
+				References: entry.References,
+				MatchedOn:  hash,
+				Source:     db.sources[i],
+			})
+		}
+	}
+
+	add(db.bySHA256[fp.SHA256], "sha256")
+	add(db.byMD5[fp.MD5], "md5")
+	if fingerprintMMH3Profiles[fp.HashProfile] {
+		add(db.byMMH3[fp.MMH3], "mmh3")
+	}
+	return matches
+}
+
+// Identify the products behind a target's favicons. With -all every
+// unique icon is looked up.
+func (db *fingerprintDB) identify(result *AnalysisResult) []ProductMatch {
+	matches := db.lookup(result.Fingerprint)
+	for _, icon := range result.Icons {
+		for _, match := range db.lookup(icon.Fingerprint) {
+			if !hasProduct(matches, match.Product) {
+				matches = append(matches, match)
+			}
+		}
+	}
+	return matches
+}
+
+func hasProduct(matches []ProductMatch, product string) bool {
+	for _, match := range matches {
+		if match.Product == product {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe a match as "Jenkins (Jenkins project, ci-cd)"
+func (m ProductMatch) String() string {
+	if details := joinNonEmpty(", ", m.Vendor, m.Category); details != "" {
+		return m.Product + " (" + details + ")"
+	}
+	return m.Product
+}
+
+// fileValues is a repeatable flag collecting file paths
+type fileValues []string
+
+func (v *fileValues) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *fileValues) Set(s string) error {
+	*v = append(*v, s)
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuiltinFingerprints(t *testing.T) {
+	db, err := loadFingerprintDB(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		fp        *Fingerprint
+		product   string
+		matchedOn string
+	}{
+		{"grafana mmh3", &Fingerprint{MMH3: 2123863676, HashProfile: "shodan"}, "Grafana", "mmh3"},
+		{"jenkins mmh3 under fofa", &Fingerprint{MMH3: 81586312, HashProfile: "fofa"}, "Jenkins", "mmh3"},
+		{"mmh3 ignored under raw", &Fingerprint{MMH3: 81586312, HashProfile: "raw"}, "", ""},
+		{"tomcat md5 under raw", &Fingerprint{MD5: "4644f2d45601037b8423d45e13194c93", HashProfile: "raw"}, "Apache Tomcat", "md5"},
+		{"md5 beats mmh3", &Fingerprint{MMH3: -297069493, MD5: "4644f2d45601037b8423d45e13194c93", HashProfile: "shodan"}, "Apache Tomcat", "md5"},
+		{"unknown", &Fingerprint{MMH3: 1, HashProfile: "shodan"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := db.lookup(tt.fp)
+			if tt.product == "" {
+				if len(matches) != 0 {
+					t.Errorf("lookup() = %v, want no match", matches)
+				}
+				return
+			}
+			if len(matches) != 1 || matches[0].Product != tt.product || matches[0].MatchedOn != tt.matchedOn {
+				t.Errorf("lookup() = %+v, want %s on %s", matches, tt.product, tt.matchedOn)
+			}
+		})
+	}
+}
+
+func TestFingerprintLookupPriority(t *testing.T) {
+	profile, err := getHashProfile("shodan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, err := calculateFingerprint([]byte("favicon"), profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Listed weakest first, so the order of the results comes from the
+	// hash priority rather than the file
+	entries := fmt.Sprintf(`
+- product: By MMH3
+  mmh3: [%[1]d]
+- product: By MD5
+  md5: [%[2]s]
+  mmh3: [%[1]d]
+- product: By SHA-256
+  sha256: [%[3]s]
+  md5: [%[2]s]
+  mmh3: [%[1]d]
+`, fp.MMH3, strings.ToUpper(fp.MD5), fp.SHA256)
+	file := filepath.Join(t.TempDir(), "custom.yaml")
+	if err := os.WriteFile(file, []byte(entries), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := loadFingerprintDB([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, match := range db.lookup(fp) {
+		if match.Source != file {
+			t.Errorf("%s came from %s, want %s", match.Product, match.Source, file)
+		}
+		got = append(got, match.Product+"="+match.MatchedOn)
+	}
+	want := "By SHA-256=sha256 By MD5=md5 By MMH3=mmh3"
+	if strings.Join(got, " ") != want {
+		t.Errorf("lookup() = %v, want %s", got, want)
+	}
+}
+
+func TestLoadFingerprintDBInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"missing product", "bad.yaml", "- mmh3: [1]\n"},
+		{"no hashes", "bad.yaml", "- product: Nothing\n"},
+		{"malformed json", "bad.json", `[{"product": "Broken"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadFingerprintDB([]string{file}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// Entries written with yaml.Marshal use the same keys the loader reads
+func TestKnownFaviconYAMLRoundTrip(t *testing.T) {
+	entries := []KnownFavicon{{
+		Product:    "Example",
+		References: []string{"https://example.com"},
+		MMH3:       []int32{-1},
+		SHA256:     []string{"abc"},
+	}}
+	data, err := yaml.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "sha256:") || strings.Contains(string(data), "vendor") {
+		t.Errorf("unexpected YAML:\n%s", data)
+	}
+
+	file := filepath.Join(t.TempDir(), "custom.yaml")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := loadFingerprintDB([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches := db.lookup(&Fingerprint{SHA256: "abc"}); len(matches) != 1 || matches[0].Product != "Example" {
+		t.Errorf("lookup() = %+v, want Example", matches)
+	}
+}
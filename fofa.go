@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const fofaPageSize = 100
+
+// Fields requested for each FOFA result, in result-row order
+var fofaFields = []string{
+	"ip", "port", "host", "domain", "country_name", "city", "lastupdatetime",
+	"as_number", "as_organization", "title", "server", "base_protocol",
+}
+
+// fofaEngine searches FOFA by MMH3 hash, which FOFA computes the same way
+// as Shodan
+type fofaEngine struct {
+	engineBase
+}
+
+// FOFA reports API errors with status 200 and error set
+type fofaError struct {
+	Error  bool   `json:"error"`
+	ErrMsg string `json:"errmsg"`
+}
+
+type fofaAccount struct {
+	fofaError
+	IsVIP          bool `json:"isvip"`
+	VIPLevel       int  `json:"vip_level"`
+	RemainAPIQuery int  `json:"remain_api_query"`
+}
+
+type fofaSearchResponse struct {
+	fofaError
+	Size    int        `json:"size"`
+	Results [][]string `json:"results"`
+}
+
+func (e *fofaEngine) account() (*fofaAccount, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/info/my?key=%s", e.baseURL, url.QueryEscape(e.key)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var account fofaAccount
+	if err := e.f.fetchJSON(req, e.name, &account); err != nil {
+		return nil, err
+	}
+	if account.Error {
+		return nil, fmt.Errorf("FOFA API error: %s", account.ErrMsg)
+	}
+	return &account, nil
+}
+
+func (e *fofaEngine) ValidateKey() error {
+	_, err := e.account()
+	return err
+}
+
+func (e *fofaEngine) Quota() (*EngineQuota, error) {
+	account, err := e.account()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := "free"
+	if account.IsVIP {
+		plan = fmt.Sprintf("vip %d", account.VIPLevel)
+	}
+	return &EngineQuota{Plan: plan, Remaining: account.RemainAPIQuery, Unit: "queries"}, nil
+}
+
+func (e *fofaEngine) Query(fp *Fingerprint) string {
+	return fmt.Sprintf(`icon_hash="%d"`, fp.MMH3)
+}
+
+func (e *fofaEngine) ManualSearchURL(fp *Fingerprint) string {
+	qbase64 := base64.StdEncoding.EncodeToString([]byte(e.Query(fp)))
+	return "https://en.fofa.info/result?qbase64=" + url.QueryEscape(qbase64)
+}
+
+func (e *fofaEngine) SearchHash(fp *Fingerprint) (*SearchResult, error) {
+	qbase64 := base64.StdEncoding.EncodeToString([]byte(e.Query(fp)))
+
+	return e.f.collectPages(fofaPageSize, func(page int) ([]Host, int, error) {
+		params := url.Values{
+			"key":     {e.key},
+			"qbase64": {qbase64},
+			"fields":  {strings.Join(fofaFields, ",")},
+			"page":    {strconv.Itoa(page)},
+			"size":    {strconv.Itoa(fofaPageSize)},
+		}
+		req, err := http.NewRequest("GET", e.baseURL+"/api/v1/search/all?"+params.Encode(), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var resp fofaSearchResponse
+		if err := e.f.fetchJSON(req, e.name, &resp); err != nil {
+			return nil, 0, err
+		}
+		if resp.Error {
+			return nil, 0, fmt.Errorf("FOFA API error: %s", resp.ErrMsg)
+		}
+
+		var hosts []Host
+		for _, row := range resp.Results {
+			if len(row) < len(fofaFields) {
+				continue
+			}
+
+			// Name each value so the raw record is self-describing
+			fields := make(map[string]string, len(fofaFields))
+			for i, name := range fofaFields {
+				fields[name] = row[i]
+			}
+
+			port, _ := strconv.Atoi(fields["port"])
+			host := Host{
+				IP:         fields["ip"],
+				Port:       port,
+				Transport:  fields["base_protocol"],
+				Org:        fields["as_organization"],
+				ASN:        formatASN(fields["as_number"]),
+				Country:    fields["country_name"],
+				City:       fields["city"],
+				HTTPTitle:  fields["title"],
+				HTTPServer: fields["server"],
+				LastUpdate: fields["lastupdatetime"],
+			}
+			if hostname := fofaHostname(fields["host"]); hostname != "" && hostname != host.IP {
+				host.Hostnames = []string{hostname}
+			}
+			if fields["domain"] != "" {
+				host.Domains = []string{fields["domain"]}
+			}
+			if raw, err := json.Marshal(fields); err == nil {
+				e.f.attachRaw(&host, e.name, raw)
+			}
+			hosts = append(hosts, host)
+		}
+		return hosts, resp.Size, nil
+	})
+}
+
+// FOFA's host field may carry a scheme and port (https://example.com:8443)
+func fofaHostname(host string) string {
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	return host
+}
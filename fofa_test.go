@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFofaSearch(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, _ := base64.StdEncoding.DecodeString(r.URL.Query().Get("qbase64"))
+		if string(query) != `icon_hash="116323821"` || r.URL.Query().Get("key") != "key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+
+		// A full first page, then a short second one
+		var rows []string
+		count := fofaPageSize
+		if page == "2" {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			rows = append(rows, fmt.Sprintf(`["192.0.2.%d", "8443", "https://app.example.com:8443", "example.com",
+				"Germany", "Berlin", "2024-05-01 00:00:00", "64500", "Example GmbH", "Login", "nginx", "tcp"]`, i))
+		}
+		fmt.Fprintf(w, `{"error": false, "size": %d, "results": [%s]}`, fofaPageSize+1, strings.Join(rows, ","))
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"fofa"},
+		EngineKeys: map[string]string{"fofa": "key"},
+		EngineURLs: map[string]string{"fofa": server.URL},
+		Pages:      3,
+	})
+
+	result, err := f.engines[0].SearchHash(&Fingerprint{MMH3: 116323821})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("fetched pages %v, want 1,2", pages)
+	}
+	if result.Total != fofaPageSize+1 || len(result.Hosts) != fofaPageSize+1 || !result.Complete {
+		t.Errorf("total %d, %d hosts, complete %v; want %d of %d, complete",
+			result.Total, len(result.Hosts), result.Complete, fofaPageSize+1, fofaPageSize+1)
+	}
+
+	host := result.Hosts[0]
+	if host.IP != "192.0.2.0" || host.Port != 8443 || host.ASN != "AS64500" || host.Org != "Example GmbH" {
+		t.Errorf("host = %+v", host)
+	}
+	if len(host.Hostnames) != 1 || host.Hostnames[0] != "app.example.com" {
+		t.Errorf("hostnames = %v, want [app.example.com]", host.Hostnames)
+	}
+	if host.HTTPTitle != "Login" || host.HTTPServer != "nginx" || host.Transport != "tcp" {
+		t.Errorf("http fields = %q, %q, %q", host.HTTPTitle, host.HTTPServer, host.Transport)
+	}
+}
+
+func TestFofaQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/info/my" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"error": false, "isvip": true, "vip_level": 2, "remain_api_query": 4321}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"fofa"},
+		EngineKeys: map[string]string{"fofa": "key"},
+		EngineURLs: map[string]string{"fofa": server.URL},
+	})
+
+	quota, err := f.engines[0].Quota()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Plan != "vip 2" || quota.Remaining != 4321 {
+		t.Errorf("quota = %+v, want vip 2 with 4321 remaining", quota)
+	}
+}
+
+func TestFofaAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		// FOFA reports most errors with status 200 and error set
+		{"error flag", http.StatusOK, `{"error": true, "errmsg": "[820031] insufficient credits"}`, "FOFA API error: [820031]"},
+		{"error status", http.StatusUnauthorized, `{"error": true, "errmsg": "invalid key"}`, "status 401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			f := newTestFinder(t, &Config{
+				Engines:    []string{"fofa"},
+				EngineKeys: map[string]string{"fofa": "key"},
+				EngineURLs: map[string]string{"fofa": server.URL},
+			})
+
+			if _, err := f.engines[0].SearchHash(&Fingerprint{MMH3: 1}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("SearchHash() error = %v, want %q", err, tt.want)
+			}
+			if _, err := f.engines[0].Quota(); err == nil {
+				t.Error("Quota() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spaolacci/murmur3"
+)
+
+const (
+	defaultHashProfile = "shodan"
+	mimeLineLength     = 76
+)
+
+// HashProfile describes how favicon bytes are encoded before MMH3 hashing.
+// Search engines disagree on the exact encoding, and a single byte of
+// difference (a missing newline) produces a completely different hash.
+type HashProfile struct {
+	Name        string
+	Description string
+	Encode      func(data []byte) []byte
+}
+
+var hashProfiles = map[string]*HashProfile{
+	"shodan": {
+		Name:        "shodan",
+		Description: "MIME base64 (76 chars per line, trailing newline) as used by Shodan's http.favicon.hash",
+		Encode:      encodeBytes,
+	},
+	"raw": {
+		Name:        "raw",
+		Description: "Standard base64 on a single line without newlines",
+		Encode: func(data []byte) []byte {
+			return []byte(base64.StdEncoding.EncodeToString(data))
+		},
+	},
+	"fofa": {
+		// FOFA's icon_hash is computed over the same encodebytes output as
+		// Shodan. It is kept as a separate profile so FOFA pivots can be
+		// named explicitly and adjusted independently if FOFA diverges.
+		Name:        "fofa",
+		Description: "MIME base64 as used by FOFA's icon_hash",
+		Encode:      encodeBytes,
+	},
+}
+
+// Fingerprint holds every hash computed from a single favicon
+type Fingerprint struct {
+	MMH3        int32  `json:"mmh3"`
+	HashProfile string `json:"hash_profile"`
+	MD5         string `json:"md5"`
+	SHA1        string `json:"sha1"`
+	SHA256      string `json:"sha256"`
+	AHash       string `json:"ahash,omitempty"`
+	DHash       string `json:"dhash,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// Get hash profile by name
+func getHashProfile(name string) (*HashProfile, error) {
+	if name == "" {
+		name = defaultHashProfile
+	}
+	profile, ok := hashProfiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash profile %q (available: %s)",
+			name, strings.Join(hashProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// List available hash profile names
+func hashProfileNames() []string {
+	names := make([]string, 0, len(hashProfiles))
+	for name := range hashProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeBytes mirrors Python's base64.encodebytes: standard base64 with a
+// newline after every 76 characters and after the final line.
+func encodeBytes(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	b.Grow(len(encoded) + len(encoded)/mimeLineLength + 1)
+	for len(encoded) > mimeLineLength {
+		b.WriteString(encoded[:mimeLineLength])
+		b.WriteByte('\n')
+		encoded = encoded[mimeLineLength:]
+	}
+	if len(encoded) > 0 {
+		b.WriteString(encoded)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// Calculate MMH3 hash
+func calculateMMH3(data []byte, profile *HashProfile) (int32, error) {
+	if len(data) == 0 {
+		return 0, fmt.Errorf("empty favicon data")
+	}
+	if profile == nil {
+		profile = hashProfiles[defaultHashProfile]
+	}
+
+	hash := murmur3.Sum32(profile.Encode(data))
+	return int32(hash), nil
+}
+
+// Calculate all favicon fingerprints. Perceptual hashes are left empty when
+// the icon cannot be decoded as a raster image (SVG, corrupt files).
+func calculateFingerprint(data []byte, profile *HashProfile) (*Fingerprint, error) {
+	if profile == nil {
+		profile = hashProfiles[defaultHashProfile]
+	}
+
+	mmh3, err := calculateMMH3(data, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	md5Sum := md5.Sum(data)
+	sha1Sum := sha1.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+
+	fp := &Fingerprint{
+		MMH3:        mmh3,
+		HashProfile: profile.Name,
+		MD5:         hex.EncodeToString(md5Sum[:]),
+		SHA1:        hex.EncodeToString(sha1Sum[:]),
+		SHA256:      hex.EncodeToString(sha256Sum[:]),
+		Size:        len(data),
+	}
+
+	if img, err := decodeFavicon(data); err == nil {
+		fp.AHash = fmt.Sprintf("%016x", averageHash(img))
+		fp.DHash = fmt.Sprintf("%016x", differenceHash(img))
+	}
+
+	return fp, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+func sequentialBytes(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+func TestCalculateMMH3(t *testing.T) {
+	banner, err := os.ReadFile("screenshots/banner.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		profile string
+		want    int32
+	}{
+		{"banner shodan", banner, "shodan", -1798949788},
+		{"banner fofa", banner, "fofa", -1798949788},
+		{"banner raw", banner, "raw", 1731530972},
+		{"short shodan", []byte("favicon"), "shodan", 1051234394},
+		{"short raw", []byte("favicon"), "raw", 238729047},
+		{"multi-line shodan", sequentialBytes(256), "shodan", -757223386},
+		{"multi-line raw", sequentialBytes(256), "raw", -741843482},
+		{"exact line shodan", make([]byte, 57), "shodan", 1993561383},
+		{"exact line raw", make([]byte, 57), "raw", 158627565},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := getHashProfile(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := calculateMMH3(tt.data, profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("calculateMMH3() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateMMH3Empty(t *testing.T) {
+	if _, err := calculateMMH3(nil, nil); err == nil {
+		t.Error("expected an error for empty data")
+	}
+}
+
+func TestEncodeBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		lines int
+	}{
+		{"empty", 0, 0},
+		{"short", 7, 1},
+		{"exactly one line", 57, 1},
+		{"one byte over", 58, 2},
+		{"several lines", 256, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := sequentialBytes(tt.size)
+			encoded := string(encodeBytes(data))
+
+			if tt.lines == 0 {
+				if encoded != "" {
+					t.Fatalf("encodeBytes() = %q, want empty", encoded)
+				}
+				return
+			}
+			if !strings.HasSuffix(encoded, "\n") {
+				t.Errorf("missing trailing newline in %q", encoded)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(encoded, "\n"), "\n")
+			if len(lines) != tt.lines {
+				t.Fatalf("got %d lines, want %d", len(lines), tt.lines)
+			}
+			for i, line := range lines {
+				if i < len(lines)-1 && len(line) != mimeLineLength {
+					t.Errorf("line %d has %d chars, want %d", i, len(line), mimeLineLength)
+				}
+				if len(line) > mimeLineLength {
+					t.Errorf("line %d has %d chars, more than %d", i, len(line), mimeLineLength)
+				}
+			}
+
+			decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\n", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded, data) {
+				t.Error("encoded data does not round-trip")
+			}
+		})
+	}
+}
+
+func TestGetHashProfile(t *testing.T) {
+	for _, name := range []string{"", "shodan", "SHODAN", "raw", "fofa"} {
+		if _, err := getHashProfile(name); err != nil {
+			t.Errorf("getHashProfile(%q): %v", name, err)
+		}
+	}
+	if _, err := getHashProfile("md5"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+// A favicon served by real sites: gitweb's git-favicon.png, as shipped
+// with git. The MMH3 values are what Shodan's reference recipe,
+// mmh3.hash(base64.encodebytes(data)) in Python, gives for these bytes.
+func TestCalculateFingerprintKnownFavicon(t *testing.T) {
+	data, err := os.ReadFile("testdata/git-favicon.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile string
+		mmh3    int32
+	}{
+		{"shodan", -1262113920},
+		{"fofa", -1262113920},
+		{"raw", -1958206763},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			profile, err := getHashProfile(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fp, err := calculateFingerprint(data, profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fp.MMH3 != tt.mmh3 || fp.HashProfile != tt.profile {
+				t.Errorf("mmh3 = %d (%s), want %d (%s)", fp.MMH3, fp.HashProfile, tt.mmh3, tt.profile)
+			}
+			if fp.MD5 != "630fd66efb2c736010c6bca81c729b34" ||
+				fp.SHA256 != "1804b48a915671fb8566d9723d96e4550aa7b7e75c3ee3c564eee2653a9d24a3" ||
+				fp.Size != len(data) {
+				t.Errorf("fingerprint = %+v, want the fixture's md5, sha256 and size", fp)
+			}
+		})
+	}
+}
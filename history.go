@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Check whether a history entry matches a filter: a substring of the
+// target or favicon URL, or one of its hashes
+func historyFilterMatches(entry HashResult, filter string) bool {
+	lower := strings.ToLower(filter)
+	if strings.Contains(strings.ToLower(entry.URL), lower) || strings.Contains(strings.ToLower(entry.FaviconURL), lower) {
+		return true
+	}
+	if hashType, fp, err := parseLookupHash(filter); err == nil {
+		return historyMatches(entry, hashType, fp)
+	}
+	return false
+}
+
+// Check whether a history entry passes every filter
+func historySelected(entry HashResult, filters []string, failedOnly bool) bool {
+	if failedOnly && entry.Success {
+		return false
+	}
+	for _, filter := range filters {
+		if !historyFilterMatches(entry, filter) {
+			return false
+		}
+	}
+	return true
+}
+
+func outputHistory(entries []HashResult, total int) {
+	infoColor.Printf("[*] Showing %d of %d history entries\n\n", len(entries), total)
+	for _, entry := range entries {
+		when := entry.DateTime.Format("2006-01-02 15:04:05")
+		if !entry.Success {
+			errorColor.Printf("%s  %-20s  %s  (%s)\n", when, "failed", entry.URL, entry.ErrorMessage)
+			continue
+		}
+
+		hash := strconv.Itoa(int(entry.Hash))
+		if entry.Fingerprint != nil && entry.Fingerprint.HashProfile != "" {
+			hash += " (" + entry.Fingerprint.HashProfile + ")"
+		}
+		line := fmt.Sprintf("%s  %-20s  %s", when, hash, entry.URL)
+		if entry.FaviconURL != "" {
+			line += "  " + displayURL(entry.FaviconURL)
+		}
+		resultColor.Println(line)
+	}
+}
+
+// Flags of the history subcommand
+func historyCommand(flags *flag.FlagSet) func(args []string) error {
+	limit := flags.Int("n", 20, "Number of entries to show (0 = all)")
+	outputFormat := flags.String("o", "text", "Output format (text, json)")
+	failedOnly := flags.Bool("failed", false, "Only show targets that failed")
+	clearEntries := flags.Bool("clear", false, "Delete the matching entries (all without filters) instead of showing them")
+	noColor := flags.Bool("no-color", false, "Disable colored output")
+
+	return func(filters []string) error {
+		setupOutput(*noColor, false)
+
+		f := &FaviconFinder{config: &Config{}}
+		f.loadHistory()
+		entries := f.history.Hashes
+
+		if *clearEntries {
+			kept := make([]HashResult, 0, len(entries))
+			for _, entry := range entries {
+				if !historySelected(entry, filters, *failedOnly) {
+					kept = append(kept, entry)
+				}
+			}
+			f.history.Hashes = kept
+			f.saveHistory()
+			successColor.Printf("[+] Removed %d of %d history entries\n", len(entries)-len(kept), len(entries))
+			return nil
+		}
+
+		// Newest first
+		var matched []HashResult
+		for i := len(entries) - 1; i >= 0; i-- {
+			if historySelected(entries[i], filters, *failedOnly) {
+				matched = append(matched, entries[i])
+			}
+		}
+
+		if len(entries) == 0 {
+			warnColor.Printf("[!] No history in %s\n", historyFile)
+			return nil
+		}
+		if *limit > 0 && len(matched) > *limit {
+			matched = matched[:*limit]
+		}
+
+		switch *outputFormat {
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if matched == nil {
+				matched = []HashResult{}
+			}
+			return encoder.Encode(matched)
+		case "text":
+			outputHistory(matched, len(entries))
+			return nil
+		default:
+			return fmt.Errorf("unsupported output format %q for history (use text or json)", *outputFormat)
+		}
+	}
+}
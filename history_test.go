@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestHistoryFilterMatches(t *testing.T) {
+	entry := HashResult{
+		URL:        "https://Portal.example.com",
+		FaviconURL: "https://portal.example.com/static/favicon.ico",
+		Hash:       -484577076,
+		Success:    true,
+		Fingerprint: &Fingerprint{
+			MD5:    "4644f2d45601037b8423d45e13194c93",
+			SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"portal.EXAMPLE", true},
+		{"static/favicon", true},
+		{"-484577076", true},
+		{"3810390220", true},
+		{"4644F2D45601037B8423D45E13194C93", true},
+		{"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", true},
+		{"-484577", false},
+		{"81586312", false},
+		{"example.org", false},
+	}
+
+	for _, tt := range tests {
+		if got := historyFilterMatches(entry, tt.filter); got != tt.want {
+			t.Errorf("historyFilterMatches(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	hunterPageSize = 100
+
+	// Hunter.how requires a time window; search the last month
+	hunterWindow = 30 * 24 * time.Hour
+)
+
+// hunterEngine searches Hunter.how by favicon MD5
+type hunterEngine struct {
+	engineBase
+}
+
+type hunterItem struct {
+	IP                string      `json:"ip"`
+	Port              int         `json:"port"`
+	Domain            string      `json:"domain"`
+	WebTitle          string      `json:"web_title"`
+	TransportProtocol string      `json:"transport_protocol"`
+	Company           string      `json:"company"`
+	ASOrg             string      `json:"as_org"`
+	ASN               interface{} `json:"asn"`
+	ISP               string      `json:"isp"`
+	Country           string      `json:"country"`
+	City              string      `json:"city"`
+	UpdatedAt         string      `json:"updated_at"`
+}
+
+type hunterSearchResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Total int               `json:"total"`
+		List  []json.RawMessage `json:"list"`
+	} `json:"data"`
+}
+
+// Hunter.how has no account endpoint, so the key is checked by the first
+// search and the quota is not reported up front
+func (e *hunterEngine) ValidateKey() error {
+	if e.key == "" {
+		return fmt.Errorf("missing API key")
+	}
+	return nil
+}
+
+func (e *hunterEngine) Quota() (*EngineQuota, error) {
+	return nil, nil
+}
+
+func (e *hunterEngine) Query(fp *Fingerprint) string {
+	return fmt.Sprintf(`favicon_hash=="%s"`, fp.MD5)
+}
+
+func (e *hunterEngine) ManualSearchURL(fp *Fingerprint) string {
+	return "https://hunter.how/list?searchValue=" + url.QueryEscape(e.Query(fp))
+}
+
+func (e *hunterEngine) SearchHash(fp *Fingerprint) (*SearchResult, error) {
+	query := base64.URLEncoding.EncodeToString([]byte(e.Query(fp)))
+	end := time.Now()
+	start := end.Add(-hunterWindow)
+
+	return e.f.collectPages(hunterPageSize, func(page int) ([]Host, int, error) {
+		params := url.Values{
+			"api-key":    {e.key},
+			"query":      {query},
+			"page":       {strconv.Itoa(page)},
+			"page_size":  {strconv.Itoa(hunterPageSize)},
+			"start_time": {start.Format("2006-01-02")},
+			"end_time":   {end.Format("2006-01-02")},
+		}
+		req, err := http.NewRequest("GET", e.baseURL+"/search?"+params.Encode(), nil)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var resp hunterSearchResponse
+		if err := e.f.fetchJSON(req, e.name, &resp); err != nil {
+			return nil, 0, err
+		}
+		if resp.Code != 200 {
+			return nil, 0, fmt.Errorf("Hunter.how API error (code %d): %s", resp.Code, resp.Message)
+		}
+
+		var hosts []Host
+		for _, raw := range resp.Data.List {
+			var item hunterItem
+			if err := json.Unmarshal(raw, &item); err != nil {
+				e.f.debug("Skipping undecodable Hunter.how result: %v", err)
+				continue
+			}
+
+			host := Host{
+				IP:         item.IP,
+				Port:       item.Port,
+				Transport:  item.TransportProtocol,
+				Org:        item.ASOrg,
+				ASN:        formatASN(item.ASN),
+				ISP:        item.ISP,
+				Country:    item.Country,
+				City:       item.City,
+				HTTPTitle:  item.WebTitle,
+				LastUpdate: item.UpdatedAt,
+			}
+			if host.Org == "" {
+				host.Org = item.Company
+			}
+			if item.Domain != "" {
+				host.Domains = []string{item.Domain}
+			}
+			e.f.attachRaw(&host, e.name, raw)
+			hosts = append(hosts, host)
+		}
+		return hosts, resp.Data.Total, nil
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHunterSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		query, _ := base64.URLEncoding.DecodeString(params.Get("query"))
+		if string(query) != `favicon_hash=="d41d8cd98f00b204e9800998ecf8427e"` || params.Get("api-key") != "key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if params.Get("start_time") == "" || params.Get("end_time") == "" {
+			t.Error("search should carry a time window")
+		}
+		fmt.Fprint(w, `{"code": 200, "message": "success", "data": {"total": 2, "list": [{
+			"ip": "192.0.2.30", "port": 443, "domain": "example.com", "web_title": "Dashboard",
+			"transport_protocol": "tcp", "company": "Example Inc", "as_org": "Example AS",
+			"asn": 64503, "isp": "Example ISP", "country": "United States", "city": "Austin",
+			"updated_at": "2024-05-01"
+		}, {
+			"ip": "192.0.2.31", "port": 80, "company": "Fallback Co"
+		}]}}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"hunter"},
+		EngineKeys: map[string]string{"hunter": "key"},
+		EngineURLs: map[string]string{"hunter": server.URL},
+	})
+
+	result, err := f.engines[0].SearchHash(&Fingerprint{MD5: "d41d8cd98f00b204e9800998ecf8427e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || len(result.Hosts) != 2 || !result.Complete {
+		t.Fatalf("total %d, %d hosts, complete %v; want 2 of 2", result.Total, len(result.Hosts), result.Complete)
+	}
+
+	first, second := result.Hosts[0], result.Hosts[1]
+	if first.ASN != "AS64503" || first.Org != "Example AS" || first.HTTPTitle != "Dashboard" || first.City != "Austin" {
+		t.Errorf("first host = %+v", first)
+	}
+	if len(first.Domains) != 1 || first.Domains[0] != "example.com" {
+		t.Errorf("domains = %v, want [example.com]", first.Domains)
+	}
+	if second.Org != "Fallback Co" {
+		t.Errorf("org = %q, want the company when as_org is empty", second.Org)
+	}
+}
+
+// Hunter.how has no account endpoint, so the quota is unknown and a key is
+// only checked for presence
+func TestHunterQuota(t *testing.T) {
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"hunter"},
+		EngineKeys: map[string]string{"hunter": "key"},
+		EngineURLs: map[string]string{"hunter": "http://127.0.0.1:0"},
+	})
+
+	quota, err := f.engines[0].Quota()
+	if quota != nil || err != nil {
+		t.Errorf("Quota() = %+v, %v; want nil, nil", quota, err)
+	}
+	if err := f.engines[0].ValidateKey(); err != nil {
+		t.Errorf("ValidateKey() = %v", err)
+	}
+}
+
+func TestHunterAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"error code", http.StatusOK, `{"code": 401, "message": "invalid api key"}`, "code 401"},
+		{"error status", http.StatusInternalServerError, `oops`, "status 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			f := newTestFinder(t, &Config{
+				Engines:    []string{"hunter"},
+				EngineKeys: map[string]string{"hunter": "key"},
+				EngineURLs: map[string]string{"hunter": server.URL},
+			})
+
+			if _, err := f.engines[0].SearchHash(&Fingerprint{MD5: "x"}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("SearchHash() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
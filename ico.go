@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+const (
+	icoHeaderSize    = 6
+	icoEntrySize     = 16
+	bmpFileHeader    = 14
+	dibMinHeader     = 40
+	biRGB            = 0
+	biBitfields      = 3
+	pngSignature     = "\x89PNG\r\n\x1a\n"
+	icoMagic         = "\x00\x00\x01\x00"
+	bmpMagic         = "BM"
+	maxIconDimension = 1024
+)
+
+// Register ICO and BMP decoders so image.Decode can handle favicons
+func init() {
+	image.RegisterFormat("ico", icoMagic, decodeICO, decodeICOConfig)
+	image.RegisterFormat("bmp", bmpMagic, decodeBMP, decodeBMPConfig)
+}
+
+type icoEntry struct {
+	width    int
+	height   int
+	bitCount int
+	size     int
+	offset   int
+}
+
+// Parse the ICO directory and pick the largest image
+func largestICOEntry(data []byte) (*icoEntry, error) {
+	if len(data) < icoHeaderSize || string(data[:4]) != icoMagic {
+		return nil, fmt.Errorf("not an ICO file")
+	}
+
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < icoHeaderSize+count*icoEntrySize {
+		return nil, fmt.Errorf("truncated ICO directory")
+	}
+
+	var best *icoEntry
+	for i := 0; i < count; i++ {
+		raw := data[icoHeaderSize+i*icoEntrySize:]
+		entry := &icoEntry{
+			width:    int(raw[0]),
+			height:   int(raw[1]),
+			bitCount: int(binary.LittleEndian.Uint16(raw[6:8])),
+			size:     int(binary.LittleEndian.Uint32(raw[8:12])),
+			offset:   int(binary.LittleEndian.Uint32(raw[12:16])),
+		}
+		// A zero dimension means 256 pixels
+		if entry.width == 0 {
+			entry.width = 256
+		}
+		if entry.height == 0 {
+			entry.height = 256
+		}
+		if entry.offset < 0 || entry.size <= 0 || entry.offset+entry.size > len(data) {
+			continue
+		}
+
+		if best == nil ||
+			entry.width*entry.height > best.width*best.height ||
+			(entry.width*entry.height == best.width*best.height && entry.bitCount > best.bitCount) {
+			best = entry
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no usable image in ICO file")
+	}
+	return best, nil
+}
+
+func decodeICO(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := largestICOEntry(data)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := data[entry.offset : entry.offset+entry.size]
+	if bytes.HasPrefix(payload, []byte(pngSignature)) {
+		return png.Decode(bytes.NewReader(payload))
+	}
+	return decodeDIB(payload, true)
+}
+
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	entry, err := largestICOEntry(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      entry.width,
+		Height:     entry.height,
+	}, nil
+}
+
+func decodeBMP(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < bmpFileHeader+dibMinHeader || string(data[:2]) != bmpMagic {
+		return nil, fmt.Errorf("not a BMP file")
+	}
+
+	pixelOffset := int(binary.LittleEndian.Uint32(data[10:14]))
+	if pixelOffset < bmpFileHeader || pixelOffset > len(data) {
+		return nil, fmt.Errorf("invalid BMP pixel offset")
+	}
+	return decodeDIBAt(data[bmpFileHeader:], pixelOffset-bmpFileHeader, false)
+}
+
+func decodeBMPConfig(r io.Reader) (image.Config, error) {
+	header := make([]byte, bmpFileHeader+dibMinHeader)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return image.Config{}, err
+	}
+
+	width := int(int32(binary.LittleEndian.Uint32(header[18:22])))
+	height := int(int32(binary.LittleEndian.Uint32(header[22:26])))
+	if height < 0 {
+		height = -height
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: width, Height: height}, nil
+}
+
+// decodeDIB decodes a headerless bitmap as stored inside ICO files, where
+// the pixel data follows the palette directly.
+func decodeDIB(data []byte, icon bool) (image.Image, error) {
+	return decodeDIBAt(data, -1, icon)
+}
+
+// decodeDIBAt decodes a device-independent bitmap. pixelOffset is relative to
+// the start of the DIB header; a negative value means the pixels directly
+// follow the palette. Icon bitmaps store a doubled height to make room for
+// the AND mask, which is ignored here.
+func decodeDIBAt(data []byte, pixelOffset int, icon bool) (image.Image, error) {
+	if len(data) < dibMinHeader {
+		return nil, fmt.Errorf("truncated bitmap header")
+	}
+
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12])))
+	bitCount := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+
+	if headerSize < dibMinHeader || headerSize > len(data) {
+		return nil, fmt.Errorf("invalid bitmap header size %d", headerSize)
+	}
+	if compression != biRGB && !(compression == biBitfields && bitCount == 32) {
+		return nil, fmt.Errorf("unsupported bitmap compression %d", compression)
+	}
+
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	if icon {
+		height /= 2
+	}
+	if width <= 0 || height <= 0 || width > maxIconDimension || height > maxIconDimension {
+		return nil, fmt.Errorf("invalid bitmap dimensions %dx%d", width, height)
+	}
+
+	var palette []color.NRGBA
+	paletteOffset := headerSize
+	if bitCount <= 8 {
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bitCount
+		}
+		if paletteOffset+colorsUsed*4 > len(data) {
+			return nil, fmt.Errorf("truncated bitmap palette")
+		}
+		palette = make([]color.NRGBA, colorsUsed)
+		for i := range palette {
+			p := data[paletteOffset+i*4:]
+			palette[i] = color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+		}
+	} else if compression == biBitfields && headerSize == dibMinHeader {
+		// Masks follow a plain BITMAPINFOHEADER
+		paletteOffset += 12
+	}
+
+	if pixelOffset < 0 {
+		pixelOffset = paletteOffset + len(palette)*4
+	}
+
+	stride := ((width*bitCount + 31) / 32) * 4
+	if pixelOffset+stride*height > len(data) {
+		return nil, fmt.Errorf("truncated bitmap pixel data")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := data[pixelOffset+y*stride : pixelOffset+(y+1)*stride]
+		dstY := height - 1 - y
+		if topDown {
+			dstY = y
+		}
+
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bitCount {
+			case 32:
+				c = color.NRGBA{R: row[x*4+2], G: row[x*4+1], B: row[x*4], A: row[x*4+3]}
+				if c.A != 0 {
+					hasAlpha = true
+				}
+			case 24:
+				c = color.NRGBA{R: row[x*3+2], G: row[x*3+1], B: row[x*3], A: 0xff}
+			case 8:
+				c = paletteColor(palette, int(row[x]))
+			case 4:
+				c = paletteColor(palette, int(row[x/2]>>(4*(1-uint(x%2)))&0x0f))
+			case 1:
+				c = paletteColor(palette, int(row[x/8]>>(7-uint(x%8))&0x01))
+			default:
+				return nil, fmt.Errorf("unsupported bitmap depth %d", bitCount)
+			}
+			img.SetNRGBA(x, dstY, c)
+		}
+	}
+
+	// Old 32-bit icons leave the alpha channel empty and rely on the mask
+	if bitCount == 32 && !hasAlpha {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+	}
+
+	return img, nil
+}
+
+func paletteColor(palette []color.NRGBA, index int) color.NRGBA {
+	if index < len(palette) {
+		return palette[index]
+	}
+	return color.NRGBA{A: 0xff}
+}
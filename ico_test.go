@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// Build a BITMAPINFOHEADER followed by 24 or 32-bit bottom-up pixels.
+// Icon bitmaps get a doubled height and an empty AND mask.
+func buildDIB(pixels [][]color.NRGBA, bitCount int, icon bool) []byte {
+	height := len(pixels)
+	width := len(pixels[0])
+	stride := ((width*bitCount + 31) / 32) * 4
+
+	var buf bytes.Buffer
+	header := make([]byte, dibMinHeader)
+	binary.LittleEndian.PutUint32(header[0:4], dibMinHeader)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
+	storedHeight := height
+	if icon {
+		storedHeight *= 2
+	}
+	binary.LittleEndian.PutUint32(header[8:12], uint32(storedHeight))
+	binary.LittleEndian.PutUint16(header[12:14], 1)
+	binary.LittleEndian.PutUint16(header[14:16], uint16(bitCount))
+	buf.Write(header)
+
+	for y := height - 1; y >= 0; y-- {
+		row := make([]byte, stride)
+		for x, c := range pixels[y] {
+			switch bitCount {
+			case 32:
+				copy(row[x*4:], []byte{c.B, c.G, c.R, c.A})
+			case 24:
+				copy(row[x*3:], []byte{c.B, c.G, c.R})
+			}
+		}
+		buf.Write(row)
+	}
+	if icon {
+		maskStride := ((width + 31) / 32) * 4
+		buf.Write(make([]byte, maskStride*height))
+	}
+	return buf.Bytes()
+}
+
+func buildBMP(pixels [][]color.NRGBA) []byte {
+	dib := buildDIB(pixels, 24, false)
+	header := make([]byte, bmpFileHeader)
+	copy(header, bmpMagic)
+	binary.LittleEndian.PutUint32(header[2:6], uint32(bmpFileHeader+len(dib)))
+	binary.LittleEndian.PutUint32(header[10:14], bmpFileHeader+dibMinHeader)
+	return append(header, dib...)
+}
+
+// Wrap image payloads in an ICO directory, one entry per payload
+func buildICO(sizes []int, payloads ...[]byte) []byte {
+	header := make([]byte, icoHeaderSize)
+	copy(header, icoMagic)
+	binary.LittleEndian.PutUint16(header[4:6], uint16(len(payloads)))
+
+	offset := icoHeaderSize + icoEntrySize*len(payloads)
+	var directory, body []byte
+	for i, payload := range payloads {
+		entry := make([]byte, icoEntrySize)
+		entry[0], entry[1] = byte(sizes[i]), byte(sizes[i])
+		binary.LittleEndian.PutUint16(entry[6:8], 32)
+		binary.LittleEndian.PutUint32(entry[8:12], uint32(len(payload)))
+		binary.LittleEndian.PutUint32(entry[12:16], uint32(offset+len(body)))
+		directory = append(directory, entry...)
+		body = append(body, payload...)
+	}
+	return append(append(header, directory...), body...)
+}
+
+func solidPixels(size int, c color.NRGBA) [][]color.NRGBA {
+	pixels := make([][]color.NRGBA, size)
+	for y := range pixels {
+		pixels[y] = make([]color.NRGBA, size)
+		for x := range pixels[y] {
+			pixels[y][x] = c
+		}
+	}
+	return pixels
+}
+
+func encodePNG(t *testing.T, size int, c color.NRGBA) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeFavicon(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	blue := color.NRGBA{B: 0xff, A: 0xff}
+
+	// Top row red, bottom row blue, to catch flipped bottom-up decoding
+	striped := [][]color.NRGBA{{red, red}, {blue, blue}}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+		size   int
+		top    color.NRGBA
+		bottom color.NRGBA
+	}{
+		{"bmp 24-bit", buildBMP(striped), "bmp", 2, red, blue},
+		{"ico 32-bit dib", buildICO([]int{2}, buildDIB(striped, 32, true)), "ico", 2, red, blue},
+		{"ico png", buildICO([]int{4}, encodePNG(t, 4, red)), "ico", 4, red, red},
+		{"ico picks largest", buildICO([]int{2, 4}, buildDIB(solidPixels(2, blue), 32, true), encodePNG(t, 4, red)), "ico", 4, red, red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, format, err := image.DecodeConfig(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.format {
+				t.Errorf("format = %q, want %q", format, tt.format)
+			}
+
+			img, err := decodeFavicon(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bounds := img.Bounds()
+			if bounds.Dx() != tt.size || bounds.Dy() != tt.size {
+				t.Fatalf("size = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.size, tt.size)
+			}
+			if got := color.NRGBAModel.Convert(img.At(0, 0)); got != tt.top {
+				t.Errorf("top pixel = %v, want %v", got, tt.top)
+			}
+			if got := color.NRGBAModel.Convert(img.At(0, tt.size-1)); got != tt.bottom {
+				t.Errorf("bottom pixel = %v, want %v", got, tt.bottom)
+			}
+		})
+	}
+}
+
+func TestDecodeFaviconInvalid(t *testing.T) {
+	bmp := buildBMP(solidPixels(2, color.NRGBA{A: 0xff}))
+	ico := buildICO([]int{2}, buildDIB(solidPixels(2, color.NRGBA{A: 0xff}), 32, true))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"text", []byte("BM is not a bitmap")},
+		{"truncated bmp", bmp[:len(bmp)-4]},
+		{"truncated ico directory", ico[:icoHeaderSize+4]},
+		{"ico without entries", []byte("\x00\x00\x01\x00\x00\x00")},
+		{"ico entry past end", ico[:len(ico)-8]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeFavicon(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// IconResult is one unique favicon found while enumerating candidates.
+// Candidates serving identical bytes are folded into a single result.
+type IconResult struct {
+	URL         string       `json:"url"`
+	URLs        []string     `json:"urls,omitempty"`
+	Sources     []string     `json:"sources"`
+	Sizes       string       `json:"sizes,omitempty"`
+	MIMEType    string       `json:"mime_type"`
+	Fingerprint *Fingerprint `json:"fingerprint"`
+
+	data []byte
+}
+
+// Check a Content-Type header against the accepted favicon types
+func isValidFaviconType(contentType string) bool {
+	for _, validType := range validFaviconTypes {
+		if strings.HasPrefix(contentType, validType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe where a candidate came from, e.g. "link:apple-touch-icon"
+func (c FaviconCandidate) label() string {
+	if c.Rel != "" {
+		return c.Source + ":" + c.Rel
+	}
+	return c.Source
+}
+
+// Download favicon bytes, returning the data, MIME type and User-Agent used
+func (f *FaviconFinder) downloadFavicon(faviconURL string) ([]byte, string, string, error) {
+	if isDataURI(faviconURL) {
+		data, mediaType, err := decodeDataURI(faviconURL)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to decode inline favicon: %v", err)
+		}
+		return data, faviconMIMEType(data, mediaType), "", nil
+	}
+
+	resp, err := f.makeRequest(faviconURL)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to download favicon: %v", err)
+	}
+	defer resp.Body.Close()
+
+	userAgent := resp.Request.Header.Get("User-Agent")
+	if resp.StatusCode != 200 {
+		return nil, "", userAgent, fmt.Errorf("failed to download favicon: got status code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", userAgent, fmt.Errorf("failed to read favicon data: %v", err)
+	}
+
+	return data, faviconMIMEType(data, resp.Header.Get("Content-Type")), userAgent, nil
+}
+
+// Build candidates for every common favicon path on the target host
+func commonPathCandidates(targetURL string, paths []string) ([]FaviconCandidate, error) {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]FaviconCandidate, 0, len(paths))
+	for _, path := range paths {
+		candidates = append(candidates, FaviconCandidate{
+			URL:    fmt.Sprintf("%s://%s%s", parsedURL.Scheme, parsedURL.Host, path),
+			Source: "path",
+		})
+	}
+	return candidates, nil
+}
+
+// Collect every link, manifest, common-path and inline candidate, download
+// each one and return the unique icons in discovery order
+func (f *FaviconFinder) enumerateIcons(targetURL string) ([]IconResult, string, error) {
+	candidates, inline, err := f.collectHTMLCandidates(targetURL)
+	if err != nil {
+		f.debug("HTML detection failed: %v", err)
+	}
+
+	// Probe the common paths concurrently and only download the hits
+	hits, err := f.probePaths(targetURL, false)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, hit := range hits {
+		candidates = append(candidates, FaviconCandidate{URL: hit, Source: "path"})
+	}
+	candidates = append(candidates, inline...)
+
+	var icons []IconResult
+	var userAgent string
+	bySHA256 := make(map[string]int)
+	// Index of the icon each URL resolved to, or -1 if it failed
+	seenURL := make(map[string]int)
+
+	for _, candidate := range candidates {
+		if i, ok := seenURL[candidate.URL]; ok {
+			if i >= 0 {
+				icons[i].Sources = appendUnique(icons[i].Sources, candidate.label())
+			}
+			continue
+		}
+		seenURL[candidate.URL] = -1
+
+		data, mimeType, ua, err := f.downloadFavicon(candidate.URL)
+		if err != nil {
+			f.debug("Candidate %s: %v", candidate.URL, err)
+			continue
+		}
+		if sniffImageFormat(data) == "" {
+			f.debug("Candidate %s: not an image (content type %q)", candidate.URL, mimeType)
+			continue
+		}
+
+		fingerprint, err := calculateFingerprint(data, f.hashProfile)
+		if err != nil {
+			f.debug("Candidate %s: %v", candidate.URL, err)
+			continue
+		}
+
+		if i, ok := bySHA256[fingerprint.SHA256]; ok {
+			seenURL[candidate.URL] = i
+			icons[i].URLs = append(icons[i].URLs, candidate.URL)
+			icons[i].Sources = appendUnique(icons[i].Sources, candidate.label())
+			continue
+		}
+
+		f.debug("Found unique icon %s (%s, %d bytes, hash %d)", candidate.URL, mimeType, fingerprint.Size, fingerprint.MMH3)
+		if userAgent == "" {
+			userAgent = ua
+		}
+		bySHA256[fingerprint.SHA256] = len(icons)
+		seenURL[candidate.URL] = len(icons)
+		icons = append(icons, IconResult{
+			URL:         candidate.URL,
+			Sources:     []string{candidate.label()},
+			Sizes:       candidate.Sizes,
+			MIMEType:    mimeType,
+			Fingerprint: fingerprint,
+			data:        data,
+		})
+	}
+
+	if len(icons) == 0 {
+		return nil, userAgent, fmt.Errorf("no valid favicon found among %d candidates", len(seenURL))
+	}
+	return icons, userAgent, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnumerateIconsDedup(t *testing.T) {
+	ico := buildICO([]int{2}, buildDIB(solidPixels(2, color.NRGBA{R: 0xff, A: 0xff}), 32, true))
+	png := encodePNG(t, 4, color.NRGBA{G: 0xff, A: 0xff})
+
+	files := map[string]struct {
+		contentType string
+		data        []byte
+	}{
+		"/static/icon.ico":  {"image/x-icon", ico},
+		"/favicon.ico":      {"image/x-icon", ico},
+		"/static/touch.png": {"image/png", png},
+		"/favicon.png":      {"image/png", png},
+		"/broken.png":       {"text/html", []byte("<html>moved</html>")},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><head>
+				<link rel="shortcut icon" href="/static/icon.ico">
+				<link rel="icon" href="/static/icon.ico">
+				<link rel="apple-touch-icon" href="/static/touch.png">
+				<link rel="mask-icon" href="/broken.png">
+			</head></html>`)
+			return
+		}
+		file, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", file.contentType)
+		w.Write(file.data)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{AllCandidates: true, HostConcurrency: 8})
+	icons, _, err := f.enumerateIcons(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strip := func(urls []string) string {
+		return strings.ReplaceAll(strings.Join(urls, " "), server.URL, "")
+	}
+	want := []struct {
+		url     string
+		urls    string
+		sources string
+	}{
+		{"/static/icon.ico", "/favicon.ico", "link:shortcut icon link:icon path"},
+		{"/static/touch.png", "/favicon.png", "link:apple-touch-icon path"},
+	}
+	if len(icons) != len(want) {
+		t.Fatalf("got %d unique icons, want %d: %+v", len(icons), len(want), icons)
+	}
+	for i, w := range want {
+		icon := icons[i]
+		if strip([]string{icon.URL}) != w.url || strip(icon.URLs) != w.urls || strings.Join(icon.Sources, " ") != w.sources {
+			t.Errorf("icon %d = %s (also %s) from %v; want %s (also %s) from %s",
+				i, strip([]string{icon.URL}), strip(icon.URLs), icon.Sources, w.url, w.urls, w.sources)
+		}
+	}
+	if icons[0].Fingerprint.SHA256 == icons[1].Fingerprint.SHA256 {
+		t.Error("distinct icons share a SHA-256")
+	}
+}
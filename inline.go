@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Longest data: URI shown in text output before it is shortened
+const maxDisplayURL = 80
+
+// Icon-like CSS url() references: data URIs and paths mentioning an icon
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+
+// Check whether a favicon URL holds its bytes inline
+func isDataURI(uri string) bool {
+	return len(uri) > 5 && strings.EqualFold(uri[:5], "data:")
+}
+
+// Decode a data: URI into its bytes and media type. Both base64 and
+// percent-encoded payloads are accepted, as browsers do.
+func decodeDataURI(uri string) ([]byte, string, error) {
+	if !isDataURI(uri) {
+		return nil, "", fmt.Errorf("not a data URI")
+	}
+	header, payload, ok := strings.Cut(uri[5:], ",")
+	if !ok {
+		return nil, "", fmt.Errorf("malformed data URI: missing comma")
+	}
+
+	isBase64 := false
+	params := strings.Split(header, ";")
+	if last := params[len(params)-1]; strings.EqualFold(strings.TrimSpace(last), "base64") {
+		isBase64 = true
+		params = params[:len(params)-1]
+	}
+	mediaType, _, _ := mime.ParseMediaType(strings.Join(params, ";"))
+
+	if !isBase64 {
+		data, err := url.PathUnescape(payload)
+		if err != nil {
+			// Stray percent signs are common in hand-written SVG; keep
+			// the payload as it is
+			data = payload
+		}
+		return []byte(data), mediaType, nil
+	}
+
+	// HTML attributes may wrap long payloads and percent-encode padding
+	if unescaped, err := url.PathUnescape(payload); err == nil {
+		payload = unescaped
+	}
+	payload = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, payload)
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := encoding.DecodeString(payload); err == nil {
+			return data, mediaType, nil
+		}
+	}
+	return nil, mediaType, fmt.Errorf("malformed base64 in data URI")
+}
+
+// Build a data URI holding an inline SVG document
+func svgDataURI(markup string) string {
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(markup))
+}
+
+// Shorten data: URIs for display
+func displayURL(uri string) string {
+	if isDataURI(uri) && len(uri) > maxDisplayURL {
+		return uri[:maxDisplayURL] + fmt.Sprintf("... (%d chars)", len(uri))
+	}
+	return uri
+}
+
+// Check whether an element looks like it holds the site's icon
+func looksLikeIcon(s *goquery.Selection) bool {
+	for _, attr := range []string{"id", "class", "aria-label", "data-icon"} {
+		value, _ := s.Attr(attr)
+		value = strings.ToLower(value)
+		if strings.Contains(value, "favicon") || strings.Contains(value, "logo") {
+			return true
+		}
+	}
+	return false
+}
+
+// Collect icons embedded in the page itself: inline <svg> logos and icons
+// referenced from <style> blocks and style attributes. All are recorded
+// with source "inline".
+func (f *FaviconFinder) findInlineCandidates(targetURL string, doc *goquery.Document) []FaviconCandidate {
+	var candidates []FaviconCandidate
+	seen := make(map[string]bool)
+	add := func(candidate FaviconCandidate) {
+		if seen[candidate.URL] {
+			return
+		}
+		seen[candidate.URL] = true
+		candidates = append(candidates, candidate)
+	}
+
+	svgs := doc.Find("svg").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return looksLikeIcon(s) || looksLikeIcon(s.Parent())
+	})
+	if !f.config.AllCandidates {
+		svgs = svgs.First()
+	}
+	svgs.Each(func(_ int, s *goquery.Selection) {
+		markup, err := goquery.OuterHtml(s)
+		if err != nil {
+			return
+		}
+		// A standalone SVG document needs its namespace to render
+		if _, ok := s.Attr("xmlns"); !ok {
+			markup = strings.Replace(markup, "<svg", `<svg xmlns="http://www.w3.org/2000/svg"`, 1)
+		}
+		f.debug("Found inline svg (%d bytes)", len(markup))
+		add(FaviconCandidate{URL: svgDataURI(markup), Source: "inline", Rel: "svg", Type: "image/svg+xml"})
+	})
+
+	var styles []string
+	doc.Find("style").Each(func(_ int, s *goquery.Selection) {
+		styles = append(styles, s.Text())
+	})
+	doc.Find("[style]").Each(func(_ int, s *goquery.Selection) {
+		style, _ := s.Attr("style")
+		styles = append(styles, style)
+	})
+
+	for _, style := range styles {
+		for _, match := range cssURLPattern.FindAllStringSubmatch(style, -1) {
+			ref := strings.TrimSpace(match[1])
+			lower := strings.ToLower(ref)
+			if isDataURI(ref) {
+				if !strings.HasPrefix(lower, "data:image/") {
+					continue
+				}
+			} else if !strings.Contains(lower, "icon") && !strings.Contains(lower, "logo") {
+				continue
+			}
+
+			resolved := ref
+			if !isDataURI(ref) {
+				var err error
+				if resolved, err = f.resolveURL(targetURL, ref); err != nil {
+					continue
+				}
+			}
+			f.debug("Found CSS icon: %s", displayURL(resolved))
+			add(FaviconCandidate{URL: resolved, Source: "inline", Rel: "css"})
+		}
+		if !f.config.AllCandidates && len(candidates) > 0 {
+			break
+		}
+	}
+
+	return candidates
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDecodeDataURI(t *testing.T) {
+	tests := []struct {
+		uri       string
+		data      string
+		mediaType string
+		wantErr   bool
+	}{
+		{uri: "data:image/png;base64,iVBORw0KGgo=", data: pngSignature, mediaType: "image/png"},
+		{uri: "data:image/png;base64,iVBORw0KGgo", data: pngSignature, mediaType: "image/png"},
+		{uri: "data:image/png;base64,iVBO\n Rw0KGgo%3D", data: pngSignature, mediaType: "image/png"},
+		{uri: "DATA:image/svg+xml,%3Csvg%3E%3C/svg%3E", data: "<svg></svg>", mediaType: "image/svg+xml"},
+		{uri: "data:image/svg+xml;charset=utf-8,<svg>100%</svg>", data: "<svg>100%</svg>", mediaType: "image/svg+xml"},
+		{uri: "data:image/png;base64", wantErr: true},
+		{uri: "data:image/png;base64,!!!", wantErr: true},
+		{uri: "https://example.com/favicon.ico", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			data, mediaType, err := decodeDataURI(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decodeDataURI() = %q, want an error", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.data || mediaType != tt.mediaType {
+				t.Errorf("decodeDataURI() = %q %q, want %q %q", data, mediaType, tt.data, tt.mediaType)
+			}
+		})
+	}
+}
+
+func TestInlineIconsComeLast(t *testing.T) {
+	ico := buildICO([]int{2}, buildDIB(solidPixels(2, color.NRGBA{R: 0xff, A: 0xff}), 32, true))
+	logo := encodePNG(t, 4, color.NRGBA{B: 0xff, A: 0xff})
+	svgLogo := `<svg viewBox="0 0 10 10"><rect width="10" height="10"/></svg>`
+	dataIcon := "data:image/png;base64,iVBORw0KGgo="
+
+	pages := map[string]string{
+		"/logo-and-favicon/": `<html><body style="background: url(/img/logo.png)">
+			<div class="logo">` + svgLogo + `</div></body></html>`,
+		"/logo-only/": `<html><body><div class="site-logo">` + svgLogo + `</div></body></html>`,
+		"/data-link/": `<html><head><link rel="icon" href="` + dataIcon + `"></head><body><div class="logo">` + svgLogo + `</div></body></html>`,
+	}
+
+	var hasFavicon atomic.Bool
+	hasFavicon.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case pages[r.URL.Path] != "":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, pages[r.URL.Path])
+		case r.URL.Path == "/favicon.ico" && hasFavicon.Load():
+			w.Header().Set("Content-Type", "image/x-icon")
+			w.Write(ico)
+		case r.URL.Path == "/img/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(logo)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{HostConcurrency: 8})
+
+	// The common paths are probed on the host root, which serves a real
+	// favicon, so the page logo must not be picked
+	result := f.probeVariant(server.URL + "/logo-and-favicon/")
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if want := server.URL + "/favicon.ico"; result.FaviconURL != want {
+		t.Errorf("favicon = %s, want %s", displayURL(result.FaviconURL), want)
+	}
+
+	result = f.probeVariant(server.URL + "/data-link/")
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if result.FaviconURL != dataIcon {
+		t.Errorf("favicon = %s, want the linked data URI", displayURL(result.FaviconURL))
+	}
+
+	// Without any other favicon the page logo is the last resort
+	hasFavicon.Store(false)
+	result = f.probeVariant(server.URL + "/logo-only/")
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if !isDataURI(result.FaviconURL) || sniffImageFormat(result.favicon) != "svg" {
+		t.Errorf("favicon = %s, want the inline svg", displayURL(result.FaviconURL))
+	}
+	hasFavicon.Store(true)
+
+	// With -all the logos are kept, after every declared icon
+	all := newTestFinder(t, &Config{HostConcurrency: 8, AllCandidates: true})
+	icons, _, err := all.enumerateIcons(server.URL + "/logo-and-favicon/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []string
+	for _, icon := range icons {
+		sources = append(sources, strings.Join(icon.Sources, ","))
+	}
+	if want := "path inline:svg inline:css"; strings.Join(sources, " ") != want {
+		t.Errorf("icon sources = %v, want %s", sources, want)
+	}
+}
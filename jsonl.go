@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSON Lines record types
+const (
+	jsonlTargetRecord  = "target"
+	jsonlMatchRecord   = "match"
+	jsonlSummaryRecord = "summary"
+)
+
+// jsonlTarget is a target record. Its matches follow as separate records,
+// so Hosts is left out and only counted.
+type jsonlTarget struct {
+	Type string `json:"type"`
+	*AnalysisResult
+	Matches int `json:"matches"`
+}
+
+// jsonlMatch is one matched host, tagged with the target and hash it
+// belongs to
+type jsonlMatch struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Hash   int32  `json:"hash"`
+	MD5    string `json:"md5,omitempty"`
+	*Host
+}
+
+type jsonlSummary struct {
+	Type      string  `json:"type"`
+	Targets   int     `json:"targets"`
+	Succeeded int     `json:"succeeded"`
+	Failed    int     `json:"failed"`
+	Matches   int     `json:"matches"`
+	Elapsed   float64 `json:"elapsed_seconds"`
+}
+
+// Write a target record followed by one record per matched host
+func writeJSONL(out io.Writer, result *AnalysisResult) error {
+	encoder := json.NewEncoder(out)
+
+	target := *result
+	target.Hosts = nil
+	if err := encoder.Encode(jsonlTarget{Type: jsonlTargetRecord, AnalysisResult: &target, Matches: len(result.Hosts)}); err != nil {
+		return err
+	}
+
+	match := jsonlMatch{Type: jsonlMatchRecord, Target: result.Target}
+	if result.Fingerprint != nil {
+		match.Hash = result.Fingerprint.MMH3
+		match.MD5 = result.Fingerprint.MD5
+	}
+	for i := range result.Hosts {
+		match.Host = &result.Hosts[i]
+		if err := encoder.Encode(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the final summary record of a run
+func writeJSONLSummary(out io.Writer, results []*AnalysisResult, started time.Time) error {
+	summary := jsonlSummary{
+		Type:    jsonlSummaryRecord,
+		Targets: len(results),
+		Elapsed: time.Since(started).Seconds(),
+	}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if result.Error != "" {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+		summary.Matches += len(result.Hosts)
+	}
+	return json.NewEncoder(out).Encode(summary)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Decode each JSON line into a generic record
+func decodeJSONL(t *testing.T, data string) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestWriteJSONL(t *testing.T) {
+	result := &AnalysisResult{
+		Target:      "https://example.com",
+		FaviconURL:  "https://example.com/favicon.ico",
+		Fingerprint: &Fingerprint{MMH3: -123, MD5: "abc"},
+		Hosts: []Host{
+			{IP: "192.0.2.1", Port: 443},
+			{IP: "192.0.2.2", Port: 80, Hostnames: []string{"www.example.com"}},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := writeJSONL(&out, result); err != nil {
+		t.Fatal(err)
+	}
+	records := decodeJSONL(t, out.String())
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want a target and two matches", len(records))
+	}
+
+	target := records[0]
+	if target["type"] != "target" || target["target"] != "https://example.com" || target["matches"] != 2.0 {
+		t.Errorf("target record = %v", target)
+	}
+	if _, ok := target["hosts"]; ok {
+		t.Error("target record should leave the hosts to the match records")
+	}
+	if fp, ok := target["fingerprint"].(map[string]interface{}); !ok || fp["md5"] != "abc" {
+		t.Errorf("target fingerprint = %v", target["fingerprint"])
+	}
+
+	for i, match := range records[1:] {
+		if match["type"] != "match" || match["target"] != "https://example.com" || match["hash"] != -123.0 || match["md5"] != "abc" {
+			t.Errorf("match record %d = %v", i, match)
+		}
+		if match["ip"] != result.Hosts[i].IP {
+			t.Errorf("match record %d ip = %v, want the host fields inlined", i, match["ip"])
+		}
+	}
+	if len(result.Hosts) != 2 {
+		t.Error("writeJSONL must not modify the result")
+	}
+}
+
+func TestWriteJSONLSummary(t *testing.T) {
+	results := []*AnalysisResult{
+		{Target: "a", Hosts: []Host{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}}},
+		{Target: "b", Error: "timeout"},
+		nil,
+		{Target: "c"},
+	}
+
+	var out bytes.Buffer
+	if err := writeJSONLSummary(&out, results, time.Now().Add(-2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	records := decodeJSONL(t, out.String())
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want one summary", len(records))
+	}
+
+	summary := records[0]
+	want := map[string]float64{"targets": 4, "succeeded": 2, "failed": 1, "matches": 2}
+	for key, value := range want {
+		if summary[key] != value {
+			t.Errorf("summary %s = %v, want %v", key, summary[key], value)
+		}
+	}
+	if summary["type"] != "summary" {
+		t.Errorf("summary type = %v", summary["type"])
+	}
+	if elapsed, _ := summary["elapsed_seconds"].(float64); elapsed < 2 {
+		t.Errorf("elapsed_seconds = %v, want at least 2", summary["elapsed_seconds"])
+	}
+}
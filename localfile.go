@@ -0,0 +1,313 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Files found while walking directories and archives are skipped above this
+// size; favicons are tiny and anything larger is not worth reading
+const maxLocalFaviconSize = 10 << 20
+
+// localFile is favicon bytes read from disk, an archive member or stdin
+type localFile struct {
+	name string
+	data []byte
+	err  error
+	// Named on the command line rather than found in a directory or archive
+	explicit bool
+}
+
+// Check whether a path is a supported archive
+func archiveType(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// Check whether the paths name a single favicon rather than a batch
+func localBatch(paths []string) bool {
+	if len(paths) != 1 {
+		return true
+	}
+	if paths[0] == "-" {
+		return false
+	}
+	info, err := os.Stat(paths[0])
+	return err == nil && (info.IsDir() || archiveType(paths[0]) != "")
+}
+
+// Visit every file named by paths: "-" is stdin, directories are walked
+// recursively and zip/tar archives are read member by member
+func walkLocalFiles(paths []string, visit func(file localFile)) {
+	for _, path := range paths {
+		if path == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			visit(localFile{name: "stdin", data: data, err: err, explicit: true})
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			visit(localFile{name: path, err: err, explicit: true})
+			continue
+		}
+
+		switch {
+		case info.IsDir():
+			err := filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					visit(localFile{name: name, err: err})
+					return nil
+				}
+				if !entry.Type().IsRegular() {
+					return nil
+				}
+				if archiveType(name) != "" {
+					readArchive(name, visit)
+					return nil
+				}
+				if info, err := entry.Info(); err == nil && info.Size() > maxLocalFaviconSize {
+					return nil
+				}
+				data, err := os.ReadFile(name)
+				visit(localFile{name: name, data: data, err: err})
+				return nil
+			})
+			if err != nil {
+				visit(localFile{name: path, err: err, explicit: true})
+			}
+		case archiveType(path) != "":
+			readArchive(path, visit)
+		default:
+			data, err := os.ReadFile(path)
+			visit(localFile{name: path, data: data, err: err, explicit: true})
+		}
+	}
+}
+
+// Visit the regular files inside a zip or tar(.gz) archive. Members are
+// named "archive.zip!/path/in/archive".
+func readArchive(path string, visit func(file localFile)) {
+	var err error
+	switch archiveType(path) {
+	case "zip":
+		err = readZip(path, visit)
+	default:
+		err = readTar(path, visit)
+	}
+	if err != nil {
+		visit(localFile{name: path, err: fmt.Errorf("error reading archive: %v", err), explicit: true})
+	}
+}
+
+func readZip(path string, visit func(file localFile)) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, member := range archive.File {
+		if !member.Mode().IsRegular() || member.UncompressedSize64 > maxLocalFaviconSize {
+			continue
+		}
+		name := path + "!/" + member.Name
+
+		reader, err := member.Open()
+		if err != nil {
+			visit(localFile{name: name, err: err})
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, maxLocalFaviconSize))
+		reader.Close()
+		visit(localFile{name: name, data: data, err: err})
+	}
+	return nil
+}
+
+func readTar(path string, visit func(file localFile)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if archiveType(path) == "tar.gz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > maxLocalFaviconSize {
+			continue
+		}
+
+		data, err := io.ReadAll(archive)
+		visit(localFile{name: path + "!/" + header.Name, data: data, err: err})
+	}
+}
+
+// Fingerprint a local favicon. Files found in directories and archives
+// that are not images are skipped and return a nil result.
+func (f *FaviconFinder) analyzeFile(file localFile) (*AnalysisResult, error) {
+	result := &AnalysisResult{Target: file.name}
+	if file.err != nil {
+		result.Error = file.err.Error()
+		return result, file.err
+	}
+
+	isImage := sniffImageFormat(file.data) != ""
+	if !isImage && !file.explicit {
+		f.debug("Skipping %s: not an image", file.name)
+		return nil, nil
+	}
+
+	infoColor.Printf("\n[*] File: %s\n", file.name)
+	if !isImage {
+		warnColor.Println("[!] File does not look like an image; hashing it anyway")
+	}
+
+	fingerprint, err := calculateFingerprint(file.data, f.hashProfile)
+	if err != nil {
+		err = fmt.Errorf("failed to calculate hash: %v", err)
+		result.Error = err.Error()
+		return result, err
+	}
+	successColor.Printf("[+] Favicon MMH3 hash (%s): %d\n", f.hashProfile.Name, fingerprint.MMH3)
+
+	result.Fingerprint = fingerprint
+	result.favicon = file.data
+	result.Products = f.known.identify(result)
+	for _, match := range result.Products {
+		successColor.Printf("[+] Identified: %s\n", match)
+	}
+
+	infoColor.Println()
+	for _, engine := range f.engines {
+		infoColor.Printf("[*] %s search URL: %s\n", engineTitle(engine.Name()), engine.ManualSearchURL(fingerprint))
+	}
+	return result, nil
+}
+
+// Flags of the file subcommand
+func fileCommand(flags *flag.FlagSet) func(args []string) error {
+	var fingerprintDBs fileValues
+	engineList := flags.String("engine", defaultEngine, "Engines to print manual search URLs for, comma-separated")
+	outputFormat := flags.String("o", "text", "Output format (text, json, yaml, jsonl, csv, tsv)")
+	fields := flags.String("fields", "", "Comma-separated columns for -o csv/tsv (default: all)")
+	hashProfile := flags.String("hash-profile", defaultHashProfile, "Hash encoding profile ("+strings.Join(hashProfileNames(), ", ")+")")
+	silent := flags.Bool("silent", false, "Only print the favicon hash, one per line")
+	noColor := flags.Bool("no-color", false, "Disable colored output")
+	debug := flags.Bool("debug", false, "Enable debug output")
+	reportFile := flags.String("report", "", "Write an HTML or Markdown report (report.html, report.md)")
+	stixFile := flags.String("stix", "", "Write hashes as a STIX 2.1 bundle to this file")
+	flags.Var(&fingerprintDBs, "fingerprints", "Extra YAML or JSON fingerprint database to load (repeatable)")
+
+	return func(paths []string) error {
+		setupOutput(*noColor, *silent)
+		if len(paths) == 0 {
+			return usageError("no file given")
+		}
+
+		engines, err := parseEngineList(*engineList)
+		if err != nil {
+			return err
+		}
+
+		format := *outputFormat
+		if *silent {
+			format = "silent"
+		}
+
+		f, err := NewFaviconFinder(&Config{
+			OutputFormat:   format,
+			Fields:         *fields,
+			HashProfile:    *hashProfile,
+			Debug:          *debug,
+			NoHistory:      true,
+			BatchMode:      localBatch(paths),
+			Engines:        engines,
+			ReportFile:     *reportFile,
+			STIXFile:       *stixFile,
+			FingerprintDBs: fingerprintDBs,
+		})
+		if err != nil {
+			return err
+		}
+
+		started := time.Now()
+		var results []*AnalysisResult
+		var firstErr error
+		failed := 0
+
+		walkLocalFiles(paths, func(file localFile) {
+			result, err := f.analyzeFile(file)
+			if result == nil {
+				return
+			}
+			results = append(results, result)
+			if err != nil {
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			f.streamResult(result, err)
+		})
+
+		if len(results) == 0 {
+			return fmt.Errorf("no favicon images found")
+		}
+		f.writeRunFiles(results, started)
+
+		if !f.config.BatchMode && firstErr != nil {
+			return firstErr
+		}
+
+		switch format {
+		case "json", "yaml":
+			if err := f.outputBatch(results, format); err != nil {
+				return err
+			}
+		}
+
+		if f.config.BatchMode {
+			infoColor.Printf("\n[*] Hashed %d files (%d succeeded, %d failed)\n",
+				len(results), len(results)-failed, failed)
+		}
+		if failed == len(results) {
+			return fmt.Errorf("all %d files failed", failed)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Write a file under dir, creating its parent directories
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func buildZip(t *testing.T, members map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, data := range members {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func buildTar(t *testing.T, members map[string][]byte, compress bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var out io.WriteCloser = nopWriteCloser{&buf}
+	if compress {
+		out = gzip.NewWriter(&buf)
+	}
+	archive := tar.NewWriter(out)
+	for name, data := range members {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		archive.Write(data)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWalkLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	png := encodePNG(t, 16, color.NRGBA{R: 255, A: 255})
+	ico := buildICO([]int{16}, png)
+	oversized := make([]byte, maxLocalFaviconSize+1)
+
+	icons := filepath.Join(dir, "icons")
+	writeTestFile(t, icons, "a.png", png)
+	writeTestFile(t, icons, "sub/b.ico", ico)
+	writeTestFile(t, icons, "notes.txt", []byte("not an image"))
+	writeTestFile(t, icons, "kit.zip", buildZip(t, map[string][]byte{
+		"favicon.ico":     ico,
+		"static/logo.png": png,
+		"huge.bin":        oversized,
+	}))
+	writeTestFile(t, icons, "kit.tar.gz", buildTar(t, map[string][]byte{"img/x.png": png}, true))
+
+	// A sparse file over the size cap is skipped without being read
+	huge := writeTestFile(t, icons, "huge.png", nil)
+	if err := os.Truncate(huge, maxLocalFaviconSize+1); err != nil {
+		t.Fatal(err)
+	}
+
+	tarball := writeTestFile(t, dir, "kit.tar", buildTar(t, map[string][]byte{"y.png": png}, false))
+	missing := filepath.Join(dir, "missing.png")
+
+	var got []string
+	explicit := make(map[string]bool)
+	walkLocalFiles([]string{icons, tarball, missing}, func(file localFile) {
+		name := strings.TrimPrefix(filepath.ToSlash(file.name), filepath.ToSlash(dir)+"/")
+		if file.err != nil {
+			name += " (error)"
+		} else if len(file.data) == 0 {
+			t.Errorf("%s was visited without data", name)
+		}
+		got = append(got, name)
+		explicit[name] = file.explicit
+	})
+	sort.Strings(got)
+
+	want := []string{
+		"icons/a.png",
+		"icons/kit.tar.gz!/img/x.png",
+		"icons/kit.zip!/favicon.ico",
+		"icons/kit.zip!/static/logo.png",
+		"icons/notes.txt",
+		"icons/sub/b.ico",
+		"kit.tar!/y.png",
+		"missing.png (error)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("visited:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if explicit["icons/a.png"] || explicit["kit.tar!/y.png"] || !explicit["missing.png (error)"] {
+		t.Errorf("explicit = %v, want only the missing path named on the command line", explicit)
+	}
+}
+
+func TestReadArchiveInvalid(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "broken.zip", []byte("not a zip"))
+
+	var files []localFile
+	readArchive(path, func(file localFile) { files = append(files, file) })
+	if len(files) != 1 || files[0].err == nil || files[0].name != path {
+		t.Errorf("readArchive() visited %+v, want one error for the archive", files)
+	}
+}
+
+func TestAnalyzeFileNonImage(t *testing.T) {
+	f := newTestFinder(t, &Config{})
+	text := []byte("plain text, not an image")
+
+	// A file named on the command line is hashed whatever it holds
+	result, err := f.analyzeFile(localFile{name: "notes.txt", data: text, explicit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.Fingerprint == nil || result.Target != "notes.txt" {
+		t.Fatalf("named non-image result = %+v, want a fingerprint", result)
+	}
+
+	// The same bytes found in a directory are skipped
+	result, err = f.analyzeFile(localFile{name: "dir/notes.txt", data: text})
+	if result != nil || err != nil {
+		t.Errorf("found non-image = %+v, %v; want nil, nil", result, err)
+	}
+
+	png := encodePNG(t, 16, color.NRGBA{B: 255, A: 255})
+	result, err = f.analyzeFile(localFile{name: "dir/icon.png", data: png})
+	if err != nil || result == nil || result.Fingerprint == nil {
+		t.Errorf("found image = %+v, %v; want a fingerprint", result, err)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LookupResult is everything known locally about one favicon hash
+type LookupResult struct {
+	Query       string            `json:"query"`
+	HashType    string            `json:"hash_type"`
+	Fingerprint *Fingerprint      `json:"fingerprint"`
+	Products    []ProductMatch    `json:"products,omitempty"`
+	Sightings   []Sighting        `json:"sightings,omitempty"`
+	SearchURLs  map[string]string `json:"search_urls,omitempty"`
+}
+
+// Sighting summarises the history entries of one target that served the
+// looked-up favicon
+type Sighting struct {
+	Target      string    `json:"target"`
+	FaviconURL  string    `json:"favicon_url,omitempty"`
+	HashProfile string    `json:"hash_profile,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Count       int       `json:"count"`
+}
+
+// Work out which hash a lookup argument is: a decimal MMH3 value, or an
+// MD5, SHA-1 or SHA-256 hex digest. The returned fingerprint only has that
+// hash set; MMH3 values are taken to use the Shodan encoding.
+func parseLookupHash(value string) (string, *Fingerprint, error) {
+	value = strings.TrimSpace(value)
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// Some tools print MMH3 as an unsigned 32-bit value
+		if n >= -1<<31 && n < 1<<32 {
+			return "mmh3", &Fingerprint{MMH3: int32(n), HashProfile: defaultHashProfile}, nil
+		}
+		return "", nil, fmt.Errorf("%s is out of range for an MMH3 hash", value)
+	}
+
+	if _, err := hex.DecodeString(value); err == nil {
+		digest := strings.ToLower(value)
+		switch len(digest) {
+		case 32:
+			return "md5", &Fingerprint{MD5: digest}, nil
+		case 40:
+			return "sha1", &Fingerprint{SHA1: digest}, nil
+		case 64:
+			return "sha256", &Fingerprint{SHA256: digest}, nil
+		}
+	}
+
+	return "", nil, fmt.Errorf("%q is not an MMH3 value or an MD5, SHA-1 or SHA-256 digest", value)
+}
+
+// Check whether a fingerprint has the hash of the given type
+func hasHash(fp *Fingerprint, hashType string) bool {
+	switch hashType {
+	case "mmh3":
+		return fp.HashProfile != ""
+	case "md5":
+		return fp.MD5 != ""
+	case "sha1":
+		return fp.SHA1 != ""
+	case "sha256":
+		return fp.SHA256 != ""
+	}
+	return false
+}
+
+// Check whether a history entry served the looked-up favicon
+func historyMatches(entry HashResult, hashType string, fp *Fingerprint) bool {
+	if !entry.Success {
+		return false
+	}
+	if hashType == "mmh3" {
+		return entry.Hash == fp.MMH3
+	}
+	if entry.Fingerprint == nil {
+		return false
+	}
+	switch hashType {
+	case "md5":
+		return entry.Fingerprint.MD5 == fp.MD5
+	case "sha1":
+		return entry.Fingerprint.SHA1 == fp.SHA1
+	case "sha256":
+		return entry.Fingerprint.SHA256 == fp.SHA256
+	}
+	return false
+}
+
+// Look a hash up in the fingerprint database and the search history, and
+// build the manual search URL of every engine whose hash is known
+func (f *FaviconFinder) lookup(value string) (*LookupResult, error) {
+	hashType, fp, err := parseLookupHash(value)
+	if err != nil {
+		return nil, err
+	}
+	result := &LookupResult{Query: value, HashType: hashType, Fingerprint: fp}
+
+	// Sightings in first-seen order; the other hashes of the favicon are
+	// filled in from the history so every engine can be searched
+	index := make(map[string]int)
+	for _, entry := range f.historyEntries() {
+		if !historyMatches(entry, hashType, fp) {
+			continue
+		}
+
+		if entry.Fingerprint != nil {
+			known := entry.Fingerprint
+			if fp.MD5 == "" {
+				fp.MD5 = known.MD5
+			}
+			if fp.SHA1 == "" {
+				fp.SHA1 = known.SHA1
+			}
+			if fp.SHA256 == "" {
+				fp.SHA256 = known.SHA256
+			}
+			if fp.HashProfile == "" {
+				fp.MMH3 = known.MMH3
+				fp.HashProfile = known.HashProfile
+			}
+			if fp.Size == 0 {
+				fp.Size = known.Size
+			}
+		}
+
+		i, ok := index[entry.URL]
+		if !ok {
+			i = len(result.Sightings)
+			index[entry.URL] = i
+			sighting := Sighting{Target: entry.URL, FirstSeen: entry.DateTime}
+			if entry.Fingerprint != nil {
+				sighting.HashProfile = entry.Fingerprint.HashProfile
+			}
+			result.Sightings = append(result.Sightings, sighting)
+		}
+		sighting := &result.Sightings[i]
+		sighting.Count++
+		if entry.DateTime.After(sighting.LastSeen) {
+			sighting.LastSeen = entry.DateTime
+		}
+		if entry.FaviconURL != "" {
+			sighting.FaviconURL = entry.FaviconURL
+		}
+	}
+
+	result.Products = f.known.lookup(fp)
+
+	for _, engine := range f.engines {
+		if hasHash(fp, searchEngines[engine.Name()].hash) {
+			if result.SearchURLs == nil {
+				result.SearchURLs = make(map[string]string)
+			}
+			result.SearchURLs[engine.Name()] = engine.ManualSearchURL(fp)
+		}
+	}
+
+	return result, nil
+}
+
+// Snapshot the search history, which the serve command appends to while
+// lookups run
+func (f *FaviconFinder) historyEntries() []HashResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.history == nil {
+		return nil
+	}
+	return append([]HashResult(nil), f.history.Hashes...)
+}
+
+func (f *FaviconFinder) outputLookup(result *LookupResult) {
+	fp := result.Fingerprint
+	resultColor.Printf("\n[+] Lookup: %s (%s)\n", result.Query, result.HashType)
+	if hasHash(fp, "mmh3") {
+		resultColor.Printf("    MMH3 (%s): %d\n", fp.HashProfile, fp.MMH3)
+	}
+	for _, hash := range []struct{ name, value string }{{"MD5", fp.MD5}, {"SHA1", fp.SHA1}, {"SHA256", fp.SHA256}} {
+		if hash.value != "" {
+			resultColor.Printf("    %s: %s\n", hash.name, hash.value)
+		}
+	}
+
+	if len(result.Products) > 0 {
+		resultColor.Println("\n[+] Known products:")
+		for _, match := range result.Products {
+			resultColor.Printf("    %s, matched on %s\n", match, match.MatchedOn)
+			for _, ref := range match.References {
+				resultColor.Printf("      %s\n", ref)
+			}
+		}
+	} else {
+		warnColor.Println("\n[!] No known product for this hash")
+	}
+
+	if len(result.Sightings) > 0 {
+		resultColor.Printf("\n[+] Seen on %d target(s):\n", len(result.Sightings))
+		for _, sighting := range result.Sightings {
+			resultColor.Printf("\n    Target: %s\n", sighting.Target)
+			if sighting.FaviconURL != "" {
+				resultColor.Printf("    Favicon: %s\n", sighting.FaviconURL)
+			}
+			resultColor.Printf("    Seen: %d time(s), first %s, last %s\n", sighting.Count,
+				sighting.FirstSeen.Format("2006-01-02 15:04"), sighting.LastSeen.Format("2006-01-02 15:04"))
+		}
+	} else {
+		warnColor.Println("\n[!] Hash not found in the search history")
+	}
+
+	if len(result.SearchURLs) > 0 {
+		resultColor.Println("\n[+] Search URLs:")
+		for _, engine := range f.engines {
+			if searchURL, ok := result.SearchURLs[engine.Name()]; ok {
+				resultColor.Printf("    %s: %s\n", engineTitle(engine.Name()), searchURL)
+			}
+		}
+	}
+	for _, engine := range f.engines {
+		if _, ok := result.SearchURLs[engine.Name()]; !ok {
+			hash := searchEngines[engine.Name()].hash
+			infoColor.Printf("[*] %s searches the %s hash, which is not known for this favicon\n", engineTitle(engine.Name()), hash)
+		}
+	}
+}
+
+// Flags of the lookup subcommand
+func lookupCommand(flags *flag.FlagSet) func(args []string) error {
+	var fingerprintDBs fileValues
+	engineList := flags.String("engine", strings.Join(engineNames(), ","), "Engines to build manual search URLs for, comma-separated")
+	outputFormat := flags.String("o", "text", "Output format (text, json)")
+	noColor := flags.Bool("no-color", false, "Disable colored output")
+	flags.Var(&fingerprintDBs, "fingerprints", "Extra YAML or JSON fingerprint database to load (repeatable)")
+
+	return func(args []string) error {
+		setupOutput(*noColor, false)
+		if len(args) == 0 {
+			return usageError("no hash given")
+		}
+
+		engines, err := parseEngineList(*engineList)
+		if err != nil {
+			return err
+		}
+
+		config := &Config{Engines: engines, OutputFormat: *outputFormat}
+		f := &FaviconFinder{config: config}
+		if f.engines, err = newSearchEngines(f, config); err != nil {
+			return err
+		}
+		if f.known, err = loadFingerprintDB(fingerprintDBs); err != nil {
+			return err
+		}
+		f.loadHistory()
+
+		var results []*LookupResult
+		for _, value := range args {
+			result, err := f.lookup(value)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+
+		switch *outputFormat {
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if len(results) == 1 {
+				return encoder.Encode(results[0])
+			}
+			return encoder.Encode(results)
+		case "text":
+			for _, result := range results {
+				f.outputLookup(result)
+			}
+			return nil
+		default:
+			return fmt.Errorf("unsupported output format %q for lookup (use text or json)", *outputFormat)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestParseLookupHash(t *testing.T) {
+	tests := []struct {
+		value    string
+		hashType string
+		want     Fingerprint
+		wantErr  bool
+	}{
+		{value: "81586312", hashType: "mmh3", want: Fingerprint{MMH3: 81586312, HashProfile: defaultHashProfile}},
+		{value: "-297069493", hashType: "mmh3", want: Fingerprint{MMH3: -297069493, HashProfile: defaultHashProfile}},
+		{value: " 3997897803 ", hashType: "mmh3", want: Fingerprint{MMH3: -297069493, HashProfile: defaultHashProfile}},
+		{value: "-2147483648", hashType: "mmh3", want: Fingerprint{MMH3: -1 << 31, HashProfile: defaultHashProfile}},
+		{value: "4294967296", wantErr: true},
+		{value: "-2147483649", wantErr: true},
+		{value: "4644F2D45601037B8423D45E13194C93", hashType: "md5", want: Fingerprint{MD5: "4644f2d45601037b8423d45e13194c93"}},
+		{value: "da39a3ee5e6b4b0d3255bfef95601890afd80709", hashType: "sha1", want: Fingerprint{SHA1: "da39a3ee5e6b4b0d3255bfef95601890afd80709"}},
+		{value: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hashType: "sha256", want: Fingerprint{SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}},
+		{value: "abcdef", wantErr: true},
+		{value: "4644f2d45601037b8423d45e13194c9z", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			hashType, fp, err := parseLookupHash(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseLookupHash() = %s %+v, want an error", hashType, fp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hashType != tt.hashType || *fp != tt.want {
+				t.Errorf("parseLookupHash() = %s %+v, want %s %+v", hashType, *fp, tt.hashType, tt.want)
+			}
+		})
+	}
+}
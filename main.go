@@ -2,22 +2,17 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
-	"flag"
 	"fmt"
-	"io"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/fatih/color"
-	"github.com/spaolacci/murmur3"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,21 +27,15 @@ const (
 ╚═╝     ╚═╝  ╚═╝  ╚═══╝  ╚═╝  ╚═╝╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝
                                            v%s by @0xJosep
 `
-	historyFile     = ".favhash_history.json"
-	apiStatusFile   = ".favhash_api_status.json"
-	resultsDir      = "results"
-	maxRetries      = 5
-	rateLimitWait   = 5 * time.Second
-	creditWarnLevel = 10
-)
-
-var (
-	infoColor    = color.New(color.FgCyan)
-	successColor = color.New(color.FgGreen)
-	errorColor   = color.New(color.FgRed)
-	warnColor    = color.New(color.FgYellow)
-	resultColor  = color.New(color.FgHiMagenta)
-	debugColor   = color.New(color.FgHiCyan)
+	historyFile      = ".favhash_history.json"
+	historySaveEvery = 50
+	apiStatusFile    = ".favhash_api_status.json"
+	resultsDir       = "results"
+	maxRetries       = 5
+	rateLimitWait    = 5 * time.Second
+	creditWarnLevel  = 10
+
+	validationTimeout = 5 * time.Second
 )
 
 var userAgents = []string{
@@ -63,9 +52,11 @@ var commonFaviconPaths = []string{
 	"/favicon.gif",
 	"/favicon.svg",
 	"/assets/favicon.ico",
+	"/assets/favicon.png",
 	"/assets/images/favicon.ico",
 	"/assets/img/favicon.ico",
 	"/static/favicon.ico",
+	"/static/favicon.png",
 	"/static/images/favicon.ico",
 	"/static/img/favicon.ico",
 	"/images/favicon.ico",
@@ -84,22 +75,48 @@ var validFaviconTypes = []string{
 	"image/png",
 	"image/gif",
 	"image/svg+xml",
+	"image/webp",
+	"image/jpeg",
+	"image/bmp",
 	"application/octet-stream",
 }
 
 // Structures
 type Config struct {
-	UserAgent      string
-	Timeout        time.Duration
-	RetryCount     int
-	RetryDelay     time.Duration
-	ProxyURL       string
-	OutputFormat   string
-	FollowRedirect bool
-	Debug          bool
-	SaveResults    bool
-	BatchMode      bool
-	NoHistory      bool
+	UserAgent       string
+	UserAgentFile   string
+	UserAgentMode   string
+	Timeout         time.Duration
+	RetryCount      int
+	RetryDelay      time.Duration
+	ProxyURL        string
+	OutputFormat    string
+	FollowRedirect  bool
+	Debug           bool
+	SaveResults     bool
+	BatchMode       bool
+	NoHistory       bool
+	HashProfile     string
+	Pages           int
+	MaxResults      int
+	CreditFloor     int
+	Resume          bool
+	CountOnly       bool
+	Concurrency     int
+	RateLimit       float64
+	ProbeVariants   bool
+	AllCandidates   bool
+	PathsFile       string
+	ReplacePaths    bool
+	HostConcurrency int
+	Engines         []string
+	EngineKeys      map[string]string
+	EngineURLs      map[string]string
+	RawResults      bool
+	Fields          string
+	ReportFile      string
+	STIXFile        string
+	FingerprintDBs  []string
 }
 
 type ShodanPlanDetails struct {
@@ -122,12 +139,14 @@ type ShodanAPIInfo struct {
 }
 
 type HashResult struct {
-	URL          string    `json:"url"`
-	Hash         int32     `json:"hash"`
-	DateTime     time.Time `json:"datetime"`
-	Success      bool      `json:"success"`
-	ErrorMessage string    `json:"error_message,omitempty"`
-	ResponseTime float64   `json:"response_time"`
+	URL          string       `json:"url"`
+	FaviconURL   string       `json:"favicon_url,omitempty"`
+	Hash         int32        `json:"hash"`
+	Fingerprint  *Fingerprint `json:"fingerprint,omitempty"`
+	DateTime     time.Time    `json:"datetime"`
+	Success      bool         `json:"success"`
+	ErrorMessage string       `json:"error_message,omitempty"`
+	ResponseTime float64      `json:"response_time"`
 }
 
 type HashHistory struct {
@@ -142,30 +161,101 @@ type APIStatus struct {
 	RateLimitHit bool      `json:"rate_limit_hit"`
 }
 
+type ShodanMatch struct {
+	IP        string   `json:"ip_str"`
+	Hostnames []string `json:"hostnames"`
+	Domains   []string `json:"domains"`
+	Port      int      `json:"port"`
+	Transport string   `json:"transport"`
+	Org       string   `json:"org"`
+	ISP       string   `json:"isp"`
+	ASN       string   `json:"asn"`
+	Product   string   `json:"product"`
+	Version   string   `json:"version"`
+	Hash      int64    `json:"hash"`
+	Location  struct {
+		Country string `json:"country_name"`
+		City    string `json:"city"`
+	} `json:"location"`
+	HTTP struct {
+		Title  string `json:"title"`
+		Server string `json:"server"`
+	} `json:"http"`
+	SSL struct {
+		Cert struct {
+			Subject     map[string]string `json:"subject"`
+			Issuer      map[string]string `json:"issuer"`
+			Fingerprint struct {
+				SHA256 string `json:"sha256"`
+			} `json:"fingerprint"`
+		} `json:"cert"`
+	} `json:"ssl"`
+	Vulns      map[string]json.RawMessage `json:"vulns"`
+	Timestamp  string                     `json:"timestamp"`
+	LastUpdate string                     `json:"last_update"`
+	Tags       []string                   `json:"tags"`
+}
+
+// ShodanResponse keeps matches as raw banners so nothing is lost when they
+// are saved for resuming or attached with -raw
 type ShodanResponse struct {
-	Matches []struct {
-		IP        string   `json:"ip_str"`
-		Hostnames []string `json:"hostnames"`
-		Domains   []string `json:"domains"`
-		Port      int      `json:"port"`
-		Location  struct {
-			Country string `json:"country_name"`
-			City    string `json:"city"`
-		} `json:"location"`
-		LastUpdate string   `json:"last_update"`
-		Tags       []string `json:"tags"`
-	} `json:"matches"`
-	Total int `json:"total"`
+	Matches      []json.RawMessage `json:"matches"`
+	Total        int               `json:"total"`
+	Pages        int               `json:"pages,omitempty"`
+	CreditsSpent int               `json:"credits_spent,omitempty"`
+	Complete     bool              `json:"complete,omitempty"`
+}
+
+// FaviconCandidate is a possible favicon location and where it was found
+type FaviconCandidate struct {
+	URL     string `json:"url"`
+	Source  string `json:"source"`
+	Rel     string `json:"rel,omitempty"`
+	Sizes   string `json:"sizes,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// AnalysisResult is everything produced for a single target
+type AnalysisResult struct {
+	Target      string          `json:"target"`
+	Variant     string          `json:"variant"`
+	FaviconURL  string          `json:"favicon_url"`
+	Fingerprint *Fingerprint    `json:"fingerprint"`
+	Products    []ProductMatch  `json:"products,omitempty"`
+	UserAgent   string          `json:"user_agent,omitempty"`
+	Icons       []IconResult    `json:"icons,omitempty"`
+	Variants    []VariantResult `json:"variants,omitempty"`
+	Searches    []*SearchResult `json:"searches,omitempty"`
+	Hosts       []Host          `json:"hosts,omitempty"`
+	Error       string          `json:"error,omitempty"`
+
+	// Favicon bytes, kept for reports
+	favicon []byte
 }
 
 type FaviconFinder struct {
-	client      *http.Client
-	config      *Config
-	shodanKey   string
-	history     *HashHistory
-	apiStatus   *APIStatus
-	currentHash int32
-	startTime   time.Time
+	client       *http.Client
+	config       *Config
+	hashProfile  *HashProfile
+	userAgent    UserAgentStrategy
+	hostLimit    *hostLimiter
+	faviconPaths []string
+	engines      []SearchEngine
+	known        *fingerprintDB
+	history      *HashHistory
+	apiStatus    *APIStatus
+	apiInfo      *ShodanAPIInfo
+	rateLimit    *rateLimiter
+	table        *tableWriter
+
+	// Guards history, apiStatus and apiInfo, which are shared by workers
+	mu sync.Mutex
+
+	// History entries not yet written, and the lock that keeps history
+	// writes in order
+	historyUnsaved int
+	historyWrite   sync.Mutex
 }
 
 // Initialize directories and files
@@ -174,9 +264,28 @@ func init() {
 }
 
 // Create new FaviconFinder instance
-func NewFaviconFinder(shodanKey string, config *Config) *FaviconFinder {
+func NewFaviconFinder(config *Config) (*FaviconFinder, error) {
+	profile, err := getHashProfile(config.HashProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent, err := newUserAgentStrategy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	faviconPaths, err := loadFaviconPaths(config.PathsFile, config.ReplacePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	hostLimit := newHostLimiter(config.HostConcurrency)
+
 	transport := &http.Transport{
-		MaxIdleConns:        10,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: hostLimit.limit,
+		MaxConnsPerHost:     hostLimit.limit,
 		IdleConnTimeout:     30 * time.Second,
 		DisableCompression:  false,
 		TLSHandshakeTimeout: 10 * time.Second,
@@ -200,10 +309,35 @@ func NewFaviconFinder(shodanKey string, config *Config) *FaviconFinder {
 	}
 
 	ff := &FaviconFinder{
-		client:    client,
-		config:    config,
-		shodanKey: shodanKey,
-		startTime: time.Now(),
+		client:       client,
+		config:       config,
+		hashProfile:  profile,
+		userAgent:    userAgent,
+		hostLimit:    hostLimit,
+		faviconPaths: faviconPaths,
+		rateLimit:    newRateLimiter(config.RateLimit),
+	}
+
+	if ff.engines, err = newSearchEngines(ff, config); err != nil {
+		return nil, err
+	}
+
+	if ff.known, err = loadFingerprintDB(config.FingerprintDBs); err != nil {
+		return nil, err
+	}
+	ff.debug("Loaded %d known favicon fingerprints", ff.known.size())
+
+	switch config.OutputFormat {
+	case "csv", "tsv":
+		if ff.table, err = newTableWriter(os.Stdout, config.OutputFormat, config.Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.ReportFile != "" {
+		if _, err := reportFormat(config.ReportFile); err != nil {
+			return nil, err
+		}
 	}
 
 	if !config.NoHistory {
@@ -211,7 +345,7 @@ func NewFaviconFinder(shodanKey string, config *Config) *FaviconFinder {
 		ff.loadAPIStatus()
 	}
 
-	return ff
+	return ff, nil
 }
 
 func (f *FaviconFinder) loadHistory() {
@@ -259,22 +393,14 @@ func (f *FaviconFinder) debug(format string, args ...interface{}) {
 
 // Make HTTP request with retries and improved error handling
 func (f *FaviconFinder) makeRequest(reqURL string) (*http.Response, error) {
-	// Add www. if not present
-	if !strings.Contains(reqURL, "www.") && strings.Contains(reqURL, "://") {
-		parts := strings.Split(reqURL, "://")
-		reqURL = parts[0] + "://www." + parts[1]
-	}
-
 	req, err := http.NewRequest("GET", reqURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	// Enhanced headers
-	req.Header.Set("User-Agent", userAgents[rand.Intn(len(userAgents))])
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
 	req.Header.Set("Sec-Fetch-Dest", "document")
@@ -282,46 +408,11 @@ func (f *FaviconFinder) makeRequest(reqURL string) (*http.Response, error) {
 	req.Header.Set("Sec-Fetch-Site", "none")
 	req.Header.Set("Sec-Fetch-User", "?1")
 
-	return f.client.Do(req)
-}
-
-// Check Shodan API key and get plan information
-func (f *FaviconFinder) checkAPIKey() (*ShodanAPIInfo, error) {
-	f.debug("Checking Shodan API key status")
-	resp, err := f.makeRequest(fmt.Sprintf("https://api.shodan.io/api-info?key=%s", f.shodanKey))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("invalid API key (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	var info ShodanAPIInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return nil, fmt.Errorf("failed to decode API info: %v", err)
-	}
-
-	// Update API status
-	if f.apiStatus != nil {
-		f.apiStatus.LastCheck = time.Now()
-		f.apiStatus.IsValid = true
-		f.apiStatus.ErrorCount = 0
-		f.saveAPIStatus()
-	}
-
-	return &info, nil
-}
-
-// Generate Shodan search URL for manual search
-func (f *FaviconFinder) generateShodanSearchURL(hash int32) string {
-	return fmt.Sprintf("https://www.shodan.io/search?query=http.favicon.hash:%d", hash)
+	return f.do(req, 0)
 }
 
 // Save results to file
-func (f *FaviconFinder) saveResults(results *ShodanResponse, hash int32) error {
+func (f *FaviconFinder) saveResults(result *AnalysisResult, hash int32) error {
 	if !f.config.SaveResults {
 		return nil
 	}
@@ -329,7 +420,7 @@ func (f *FaviconFinder) saveResults(results *ShodanResponse, hash int32) error {
 	filename := filepath.Join(resultsDir, fmt.Sprintf("favhash_%d_%s.json",
 		hash, time.Now().Format("20060102_150405")))
 
-	data, err := json.MarshalIndent(results, "", "  ")
+	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -337,22 +428,18 @@ func (f *FaviconFinder) saveResults(results *ShodanResponse, hash int32) error {
 	return os.WriteFile(filename, data, 0644)
 }
 
-func (f *FaviconFinder) findFaviconInHTML(targetURL string) (string, error) {
+// Collect favicon candidates from a page. Declared icons (links, the
+// manifest and og:image) come first; inline SVG logos and CSS icons are
+// returned separately, as they are guesses to try after the common paths.
+func (f *FaviconFinder) collectHTMLCandidates(targetURL string) ([]FaviconCandidate, []FaviconCandidate, error) {
 	f.debug("Checking HTML for favicon links")
 
-	resp, err := f.makeRequest(targetURL)
+	var candidates []FaviconCandidate
+	doc, err := f.fetchDocument(targetURL)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("got status code %d", resp.StatusCode)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return "", err
+		f.debug("Failed to fetch HTML: %v", err)
+		// The manifest may still be reachable at a well-known path
+		return f.findManifestCandidates(targetURL, nil), nil, err
 	}
 
 	// Check various link tags for favicon
@@ -369,109 +456,113 @@ func (f *FaviconFinder) findFaviconInHTML(targetURL string) (string, error) {
 	}
 
 	for _, selector := range selectors {
-		selection := doc.Find(fmt.Sprintf("link[rel='%s']", selector.rel)).First()
-		if href, exists := selection.Attr(selector.attr); exists {
-			f.debug("Found %s: %s", selector.rel, href)
-			resolvedURL, err := f.resolveURL(targetURL, href)
-			if err == nil {
-				// Validate favicon
-				if f.validateFavicon(resolvedURL) {
-					return resolvedURL, nil
+		links := doc.Find(fmt.Sprintf("link[rel='%s']", selector.rel))
+		// Only the first link per rel matters unless enumerating everything
+		if !f.config.AllCandidates {
+			links = links.First()
+		}
+
+		links.Each(func(_ int, selection *goquery.Selection) {
+			if href, exists := selection.Attr(selector.attr); exists {
+				href = strings.TrimSpace(href)
+				f.debug("Found %s: %s", selector.rel, displayURL(href))
+				// Icons embedded as data: URIs are decoded, not fetched
+				if isDataURI(href) {
+					candidates = append(candidates, FaviconCandidate{
+						URL:    href,
+						Source: "link",
+						Rel:    selector.rel,
+						Sizes:  selection.AttrOr("sizes", ""),
+						Type:   selection.AttrOr("type", ""),
+					})
+					return
+				}
+				resolvedURL, err := f.resolveURL(targetURL, href)
+				if err == nil {
+					sizes, _ := selection.Attr("sizes")
+					mimeType, _ := selection.Attr("type")
+					candidates = append(candidates, FaviconCandidate{
+						URL:    resolvedURL,
+						Source: "link",
+						Rel:    selector.rel,
+						Sizes:  sizes,
+						Type:   mimeType,
+					})
 				}
 			}
-		}
+		})
 	}
 
+	// Check web app manifest
+	candidates = append(candidates, f.findManifestCandidates(targetURL, doc)...)
+
 	// Check meta tags
 	metaIcon := doc.Find("meta[property='og:image']").First()
 	if content, exists := metaIcon.Attr("content"); exists {
 		f.debug("Found og:image: %s", content)
 		resolvedURL, err := f.resolveURL(targetURL, content)
-		if err == nil && f.validateFavicon(resolvedURL) {
-			return resolvedURL, nil
+		if err == nil {
+			candidates = append(candidates, FaviconCandidate{
+				URL:    resolvedURL,
+				Source: "meta",
+				Rel:    "og:image",
+			})
 		}
 	}
 
-	return "", fmt.Errorf("no valid favicon found in HTML")
+	return candidates, f.findInlineCandidates(targetURL, doc), nil
 }
 
-func (f *FaviconFinder) checkCommonPaths(targetURL string) (string, error) {
-	f.debug("Checking common favicon paths")
-
-	parsedURL, err := url.Parse(targetURL)
+// Fetch and parse an HTML page
+func (f *FaviconFinder) fetchDocument(targetURL string) (*goquery.Document, error) {
+	resp, err := f.makeRequest(targetURL)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Prioritize ICO format first
-	priorityPaths := []string{
-		"/favicon.ico",
-		"/assets/favicon.ico",
-		"/static/favicon.ico",
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("got status code %d", resp.StatusCode)
 	}
 
-	// Check priority paths first
-	for _, path := range priorityPaths {
-		faviconURL := fmt.Sprintf("%s://%s%s", parsedURL.Scheme, parsedURL.Host, path)
-		f.debug("Trying priority path: %s", faviconURL)
+	return goquery.NewDocumentFromReader(resp.Body)
+}
 
-		if f.validateFavicon(faviconURL) {
-			return faviconURL, nil
-		}
+// Find the first valid favicon declared by a page. The page's inline
+// candidates are returned as well, for use when no other favicon is found.
+func (f *FaviconFinder) findFaviconInHTML(targetURL string) (string, []FaviconCandidate, error) {
+	candidates, inline, err := f.collectHTMLCandidates(targetURL)
+	if err != nil && len(candidates) == 0 {
+		return "", nil, err
 	}
 
-	// Only check other formats if priority paths fail
-	otherPaths := []string{
-		"/favicon.png",
-		"/assets/favicon.png",
-		"/static/favicon.png",
+	if faviconURL := f.firstValidCandidate(candidates); faviconURL != "" {
+		return faviconURL, inline, nil
 	}
+	return "", inline, fmt.Errorf("no valid favicon found in HTML")
+}
 
-	for _, path := range otherPaths {
-		faviconURL := fmt.Sprintf("%s://%s%s", parsedURL.Scheme, parsedURL.Host, path)
-		f.debug("Trying alternative path: %s", faviconURL)
-
-		if f.validateFavicon(faviconURL) {
-			return faviconURL, nil
+func (f *FaviconFinder) firstValidCandidate(candidates []FaviconCandidate) string {
+	for _, candidate := range candidates {
+		if f.validateFavicon(context.Background(), candidate.URL).Valid {
+			return candidate.URL
 		}
 	}
-
-	return "", fmt.Errorf("no valid favicon found at common paths")
+	return ""
 }
 
-// Update the validateFavicon function
-func (f *FaviconFinder) validateFavicon(faviconURL string) bool {
-	// Use shorter timeout for validation
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "HEAD", faviconURL, nil)
-	if err != nil {
-		f.debug("Failed to create request: %v", err)
-		return false
-	}
+func (f *FaviconFinder) checkCommonPaths(targetURL string) (string, error) {
+	f.debug("Checking %d common favicon paths", len(f.faviconPaths))
 
-	resp, err := f.client.Do(req)
+	hits, err := f.probePaths(targetURL, true)
 	if err != nil {
-		f.debug("Favicon validation failed: %v", err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		f.debug("Favicon returned status code: %d", resp.StatusCode)
-		return false
+		return "", err
 	}
-
-	contentType := resp.Header.Get("Content-Type")
-	for _, validType := range validFaviconTypes {
-		if strings.HasPrefix(contentType, validType) {
-			f.debug("Valid favicon found: type=%s", contentType)
-			return true
-		}
+	if len(hits) == 0 {
+		return "", fmt.Errorf("no valid favicon found at common paths")
 	}
 
-	return false
+	return hits[0], nil
 }
 
 // Resolve relative URLs
@@ -489,70 +580,176 @@ func (f *FaviconFinder) resolveURL(baseURL, path string) (string, error) {
 	return base.ResolveReference(ref).String(), nil
 }
 
-// Calculate MMH3 hash
-func calculateMMH3(data []byte) (int32, error) {
-	if len(data) == 0 {
-		return 0, fmt.Errorf("empty favicon data")
+// Output structured results. A single target keeps the single-object
+// layout; batch runs emit a list with one record per target.
+func (f *FaviconFinder) outputBatch(results []*AnalysisResult, format string) error {
+	if !f.config.BatchMode && len(results) == 1 {
+		return f.outputResults(results[0], format)
 	}
 
-	b64 := base64.StdEncoding.EncodeToString(data)
-	hash := murmur3.Sum32([]byte(b64))
-	return int32(hash), nil
-}
-
-// Search Shodan
-func (f *FaviconFinder) searchShodan(hash int32) (*ShodanResponse, error) {
-	searchURL := fmt.Sprintf("https://api.shodan.io/shodan/host/search?key=%s&query=http.favicon.hash:%d",
-		f.shodanKey, hash)
-
-	resp, err := f.makeRequest(searchURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Shodan API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	var result ShodanResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		// Check if the error is due to invalid JSON
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to parse Shodan response: %v (body: %s)", err, string(body))
+	switch format {
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		return encoder.Encode(results)
+	case "csv", "tsv":
+		for _, result := range results {
+			if err := f.table.writeResult(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "jsonl":
+		for _, result := range results {
+			if err := writeJSONL(os.Stdout, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
 	}
-
-	return &result, nil
 }
 
 // Format and output results
-func (f *FaviconFinder) outputResults(results *ShodanResponse, format string) error {
+func (f *FaviconFinder) outputResults(result *AnalysisResult, format string) error {
 	switch format {
 	case "json":
 		encoder := json.NewEncoder(os.Stdout)
 		encoder.SetIndent("", "  ")
-		return encoder.Encode(results)
+		return encoder.Encode(result)
 	case "yaml":
 		encoder := yaml.NewEncoder(os.Stdout)
-		return encoder.Encode(results)
+		return encoder.Encode(result)
+	case "csv", "tsv":
+		return f.table.writeResult(result)
+	case "jsonl":
+		return writeJSONL(os.Stdout, result)
+	case "silent":
+		if result.Fingerprint != nil {
+			fmt.Println(result.Fingerprint.MMH3)
+		}
+		return nil
 	default:
-		if results.Total > 0 {
-			resultColor.Println("\n[+] Results:")
-			for _, match := range results.Matches {
-				resultColor.Printf("\n    IP: %s\n", match.IP)
-				resultColor.Printf("    Port: %d\n", match.Port)
-				if len(match.Hostnames) > 0 {
-					resultColor.Printf("    Hostnames: %s\n", strings.Join(match.Hostnames, ", "))
+		if fp := result.Fingerprint; fp != nil {
+			resultColor.Println("\n[+] Fingerprint:")
+			resultColor.Printf("    MMH3 (%s): %d\n", fp.HashProfile, fp.MMH3)
+			resultColor.Printf("    MD5: %s\n", fp.MD5)
+			resultColor.Printf("    SHA1: %s\n", fp.SHA1)
+			resultColor.Printf("    SHA256: %s\n", fp.SHA256)
+			if fp.AHash != "" {
+				resultColor.Printf("    aHash: %s\n", fp.AHash)
+				resultColor.Printf("    dHash: %s\n", fp.DHash)
+			}
+			resultColor.Printf("    Size: %d bytes\n", fp.Size)
+		}
+
+		if len(result.Products) > 0 {
+			resultColor.Println("\n[+] Identified:")
+			for _, match := range result.Products {
+				resultColor.Printf("    %s, matched on %s\n", match, match.MatchedOn)
+				for _, ref := range match.References {
+					resultColor.Printf("      %s\n", ref)
+				}
+			}
+		}
+
+		if len(result.Icons) > 0 {
+			resultColor.Printf("\n[+] Favicons (%d unique):\n", len(result.Icons))
+			for _, icon := range result.Icons {
+				resultColor.Printf("\n    URL: %s\n", displayURL(icon.URL))
+				for _, dup := range icon.URLs {
+					resultColor.Printf("    Also at: %s\n", displayURL(dup))
+				}
+				resultColor.Printf("    Source: %s\n", strings.Join(icon.Sources, ", "))
+				resultColor.Printf("    MIME Type: %s\n", icon.MIMEType)
+				if icon.Sizes != "" {
+					resultColor.Printf("    Sizes: %s\n", icon.Sizes)
+				}
+				resultColor.Printf("    Size: %d bytes\n", icon.Fingerprint.Size)
+				resultColor.Printf("    MMH3: %d\n", icon.Fingerprint.MMH3)
+				resultColor.Printf("    MD5: %s\n", icon.Fingerprint.MD5)
+			}
+		}
+
+		if len(result.Variants) > 1 {
+			resultColor.Println("\n[+] Host variants:")
+			for _, variant := range result.Variants {
+				if variant.Fingerprint != nil {
+					resultColor.Printf("    %s -> %d (%s)\n", variant.URL, variant.Fingerprint.MMH3, displayURL(variant.FaviconURL))
+				} else {
+					resultColor.Printf("    %s -> %s\n", variant.URL, variant.Error)
+				}
+			}
+		}
+
+		for _, search := range result.Searches {
+			count := search.Count
+			if count == nil || len(count.Facets) == 0 {
+				continue
+			}
+			resultColor.Printf("\n[+] %s Summary (%d matches):\n", engineTitle(search.Engine), count.Total)
+			for _, facet := range shodanFacets {
+				values := count.Facets[facet]
+				if len(values) == 0 {
+					continue
+				}
+				resultColor.Printf("\n    %s:\n", strings.ToUpper(facet))
+				for _, v := range values {
+					resultColor.Printf("      %-40v %d\n", v.Value, v.Count)
+				}
+			}
+		}
+
+		if len(result.Hosts) > 0 {
+			resultColor.Printf("\n[+] Results (%d hosts):\n", len(result.Hosts))
+			for _, host := range result.Hosts {
+				resultColor.Printf("\n    IP: %s\n", host.IP)
+				if host.Port > 0 {
+					resultColor.Printf("    Port: %s\n", joinNonEmpty("/", fmt.Sprint(host.Port), host.Transport))
+				}
+				if len(host.Hostnames) > 0 {
+					resultColor.Printf("    Hostnames: %s\n", strings.Join(host.Hostnames, ", "))
+				}
+				if len(host.Domains) > 0 {
+					resultColor.Printf("    Domains: %s\n", strings.Join(host.Domains, ", "))
+				}
+				if org := joinNonEmpty(" ", host.Org, host.ASN); org != "" {
+					resultColor.Printf("    Organization: %s\n", org)
+				}
+				if host.ISP != "" && host.ISP != host.Org {
+					resultColor.Printf("    ISP: %s\n", host.ISP)
+				}
+				if location := joinNonEmpty(", ", host.City, host.Country); location != "" {
+					resultColor.Printf("    Location: %s\n", location)
+				}
+				if product := joinNonEmpty(" ", host.Product, host.Version); product != "" {
+					resultColor.Printf("    Product: %s\n", product)
+				}
+				if host.HTTPTitle != "" {
+					resultColor.Printf("    Title: %s\n", host.HTTPTitle)
+				}
+				if host.HTTPServer != "" {
+					resultColor.Printf("    Server: %s\n", host.HTTPServer)
+				}
+				if host.SSLSubject != "" {
+					resultColor.Printf("    SSL Subject: %s\n", host.SSLSubject)
+					resultColor.Printf("    SSL Issuer: %s\n", host.SSLIssuer)
+				}
+				if host.SSLFingerprint != "" {
+					resultColor.Printf("    SSL SHA256: %s\n", host.SSLFingerprint)
 				}
-				if len(match.Domains) > 0 {
-					resultColor.Printf("    Domains: %s\n", strings.Join(match.Domains, ", "))
+				if len(host.Vulns) > 0 {
+					resultColor.Printf("    Vulns: %s\n", strings.Join(host.Vulns, ", "))
 				}
-				resultColor.Printf("    Location: %s, %s\n", match.Location.City, match.Location.Country)
-				if len(match.Tags) > 0 {
-					resultColor.Printf("    Tags: %s\n", strings.Join(match.Tags, ", "))
+				if len(host.Tags) > 0 {
+					resultColor.Printf("    Tags: %s\n", strings.Join(host.Tags, ", "))
 				}
-				resultColor.Printf("    Last Update: %s\n", match.LastUpdate)
+				if host.LastUpdate != "" {
+					resultColor.Printf("    Last Update: %s\n", host.LastUpdate)
+				}
+				resultColor.Printf("    Engines: %s\n", strings.Join(host.Engines, ", "))
 				resultColor.Println("    ---")
 			}
 		}
@@ -560,194 +757,376 @@ func (f *FaviconFinder) outputResults(results *ShodanResponse, format string) er
 	return nil
 }
 
-func (f *FaviconFinder) analyze(targetURL string, hashOnly bool) error {
+// Check every engine's API key and quota once before any target is searched
+func (f *FaviconFinder) prepareSearch() error {
+	for _, engine := range f.engines {
+		title := engineTitle(engine.Name())
+		infoColor.Printf("\n[*] Checking %s API key status...\n", title)
+		if err := engine.ValidateKey(); err != nil {
+			return fmt.Errorf("%s API key error: %v", title, err)
+		}
+
+		quota, err := engine.Quota()
+		if err != nil {
+			warnColor.Printf("\n[!] Failed to read %s quota: %v\n", title, err)
+			continue
+		}
+
+		successColor.Printf("\n[+] %s API Key Information:\n", title)
+		if quota == nil {
+			resultColor.Println("    Quota: not reported")
+			continue
+		}
+		if quota.Plan != "" {
+			resultColor.Printf("    Plan: %s\n", quota.Plan)
+		}
+		if quota.Unit != "" {
+			resultColor.Printf("    Remaining: %d %s\n", quota.Remaining, quota.Unit)
+		}
+
+		if quota.Plan == "dev" || quota.Plan == "free" {
+			warnColor.Printf("\n[!] Warning: You are using a free/dev %s API key. Results might be limited.\n", title)
+			warnColor.Println("[!] Consider upgrading to a paid plan for full search access.")
+		}
+
+		if quota.Unit != "" && quota.Remaining < creditWarnLevel {
+			warnColor.Printf("\n[!] Warning: Low %s %s remaining (%d)\n", title, quota.Unit, quota.Remaining)
+		}
+	}
+
+	infoColor.Println()
+	return nil
+}
+
+// Record a target in the search history
+func (f *FaviconFinder) recordHistory(result *AnalysisResult, started time.Time) {
+	if f.config.NoHistory {
+		return
+	}
+
+	entry := HashResult{
+		URL:          result.Target,
+		FaviconURL:   result.FaviconURL,
+		Fingerprint:  result.Fingerprint,
+		DateTime:     time.Now(),
+		Success:      result.Error == "",
+		ErrorMessage: result.Error,
+		ResponseTime: time.Since(started).Seconds(),
+	}
+	if result.Fingerprint != nil {
+		entry.Hash = result.Fingerprint.MMH3
+	}
+
+	f.mu.Lock()
+	f.history.Hashes = append(f.history.Hashes, entry)
+	f.historyUnsaved++
+	flush := f.historyUnsaved >= historySaveEvery
+	f.mu.Unlock()
+
+	if flush {
+		f.flushHistory()
+	}
+}
+
+// Write history entries recorded since the last save. Runs flush once when
+// they finish; long batches also flush every historySaveEvery targets.
+func (f *FaviconFinder) flushHistory() {
+	f.historyWrite.Lock()
+	defer f.historyWrite.Unlock()
+
+	f.mu.Lock()
+	if f.history == nil || f.historyUnsaved == 0 {
+		f.mu.Unlock()
+		return
+	}
+	data, err := json.MarshalIndent(f.history, "", "  ")
+	f.historyUnsaved = 0
+	f.mu.Unlock()
+
+	if err == nil {
+		os.WriteFile(historyFile, data, 0644)
+	}
+}
+
+// Check whether query credits remain above the configured floor
+func (f *FaviconFinder) hasCredits() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.apiInfo == nil || f.apiInfo.QueryCredits > f.config.CreditFloor
+}
+
+// Reserve one query credit before fetching a page. Returns false when the
+// known credits have reached the configured floor.
+func (f *FaviconFinder) reserveCredit() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.apiInfo == nil {
+		return true
+	}
+	if f.apiInfo.QueryCredits <= f.config.CreditFloor {
+		return false
+	}
+	f.apiInfo.QueryCredits--
+	return true
+}
+
+// Give back a credit reserved for a page that could not be fetched
+func (f *FaviconFinder) refundCredit() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.apiInfo != nil {
+		f.apiInfo.QueryCredits++
+	}
+}
+
+// Analyze a single target. The returned result is never nil; on failure
+// its Error field is set as well.
+func (f *FaviconFinder) analyze(targetURL string, hashOnly bool) (*AnalysisResult, error) {
 	if !strings.HasPrefix(targetURL, "http") {
 		targetURL = "https://" + targetURL
 	}
 
+	started := time.Now()
+	result := &AnalysisResult{Target: targetURL}
+	fail := func(err error) (*AnalysisResult, error) {
+		result.Error = err.Error()
+		f.recordHistory(result, started)
+		return result, err
+	}
+
 	infoColor.Printf("\n[*] Target URL: %s\n", targetURL)
 
-	var apiInfo *ShodanAPIInfo
-	var err error
+	// Probe the exact host first, then any enabled variants
+	variants, err := hostVariants(targetURL, f.config.ProbeVariants)
+	if err != nil {
+		return fail(err)
+	}
 
-	// Check API key if not in hash-only mode
-	if !hashOnly {
-		infoColor.Printf("\n[*] Checking Shodan API key status...\n")
-		apiInfo, err = f.checkAPIKey()
-		if err != nil {
-			return fmt.Errorf("API key error: %v", err)
+	var primary *VariantResult
+	var probed []VariantResult
+	for _, variantURL := range variants {
+		if variantURL != targetURL {
+			f.debug("Probing host variant: %s", variantURL)
 		}
 
-		successColor.Println("\n[+] API Key Information:")
-		resultColor.Printf("    Plan: %s\n", apiInfo.Plan)
-		resultColor.Printf("    Query Credits: %d\n", apiInfo.QueryCredits)
-		resultColor.Printf("    Scan Credits: %d\n", apiInfo.ScanCredits)
-
-		if apiInfo.Plan == "dev" || apiInfo.Plan == "free" {
-			warnColor.Println("\n[!] Warning: You are using a free/dev API key. Results might be limited.")
-			warnColor.Println("[!] Consider upgrading to a paid plan for full access to Shodan search.")
+		variant := f.probeVariant(variantURL)
+		probed = append(probed, *variant)
+		if variant.Fingerprint == nil {
+			f.debug("Variant %s failed: %s", variantURL, variant.Error)
+			continue
 		}
 
-		if apiInfo.QueryCredits < creditWarnLevel {
-			warnColor.Printf("\n[!] Warning: Low query credits remaining (%d)\n", apiInfo.QueryCredits)
+		successColor.Printf("\n[+] Found favicon at: %s\n", displayURL(variant.FaviconURL))
+		if primary == nil {
+			primary = variant
 		}
+	}
 
-		fmt.Println()
+	if f.config.ProbeVariants {
+		result.Variants = probed
 	}
 
-	// Find favicon
-	var faviconURL string
+	if primary == nil {
+		return fail(fmt.Errorf("%s", probed[0].Error))
+	}
 
-	// Try HTML detection first
-	faviconURL, err = f.findFaviconInHTML(targetURL)
-	if err != nil {
-		f.debug("HTML detection failed: %v", err)
-		// Try common paths
-		faviconURL, err = f.checkCommonPaths(targetURL)
-		if err != nil {
-			return fmt.Errorf("failed to find favicon: %v", err)
+	fingerprint := primary.Fingerprint
+	hash := fingerprint.MMH3
+	successColor.Printf("[+] Favicon MMH3 hash (%s): %d\n", f.hashProfile.Name, hash)
+
+	result.Variant = primary.URL
+	result.FaviconURL = primary.FaviconURL
+	result.Fingerprint = fingerprint
+	result.UserAgent = primary.UserAgent
+	result.Icons = primary.Icons
+	result.favicon = primary.favicon
+
+	// Identify known products offline, before any search credits are spent
+	result.Products = f.known.identify(result)
+	for _, match := range result.Products {
+		successColor.Printf("[+] Identified: %s\n", match)
+	}
+
+	if f.config.ProbeVariants && variantsDiffer(probed) {
+		warnColor.Println("\n[!] Host variants serve different favicons")
+	}
+
+	// Save to history
+	f.recordHistory(result, started)
+
+	if hashOnly {
+		// Generate search URLs for manual search
+		infoColor.Println()
+		for _, engine := range f.engines {
+			infoColor.Printf("[*] %s search URL: %s\n", engineTitle(engine.Name()), engine.ManualSearchURL(fingerprint))
 		}
+		return result, nil
 	}
 
-	successColor.Printf("\n[+] Found favicon at: %s\n", faviconURL)
+	var searchErr error
+	failed := 0
+	for _, engine := range f.engines {
+		search := f.searchEngine(engine, fingerprint)
+		result.Searches = append(result.Searches, search)
+		if search.Error != "" {
+			failed++
+			if searchErr == nil {
+				searchErr = fmt.Errorf("%s", search.Error)
+			}
+		}
+	}
 
-	// Download and process favicon
-	resp, err := f.makeRequest(faviconURL)
-	if err != nil {
-		return fmt.Errorf("failed to download favicon: %v", err)
+	result.Hosts = mergeHosts(result.Searches)
+	if len(f.engines) > 1 {
+		successColor.Printf("[+] %d unique hosts across %d engines\n", len(result.Hosts), len(f.engines))
 	}
-	defer resp.Body.Close()
 
-	faviconData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read favicon data: %v", err)
+	if failed == len(f.engines) {
+		result.Error = searchErr.Error()
+		return result, searchErr
 	}
 
-	hash, err := calculateMMH3(faviconData)
-	if err != nil {
-		return fmt.Errorf("failed to calculate hash: %v", err)
+	// Save results if enabled
+	if f.config.SaveResults {
+		if err := f.saveResults(result, hash); err != nil {
+			warnColor.Printf("\n[!] Failed to save results: %v\n", err)
+		}
 	}
 
-	f.currentHash = hash
-	successColor.Printf("[+] Favicon MMH3 hash: %d\n", hash)
+	return result, nil
+}
 
-	// Save to history
-	if !f.config.NoHistory {
-		f.history.Hashes = append(f.history.Hashes, HashResult{
-			URL:          targetURL,
-			Hash:         hash,
-			DateTime:     time.Now(),
-			Success:      true,
-			ResponseTime: time.Since(f.startTime).Seconds(),
-		})
-		f.saveHistory()
+// Analyze every target and output the results. A failed target is reported
+// and recorded but does not stop the run.
+func (f *FaviconFinder) run(targets []string, hashOnly bool) error {
+	if !hashOnly {
+		if err := f.prepareSearch(); err != nil {
+			return err
+		}
 	}
 
-	if hashOnly {
-		// Generate Shodan search URL for manual search
-		searchURL := f.generateShodanSearchURL(hash)
-		infoColor.Printf("\n[*] Shodan search URL: %s\n", searchURL)
-		return nil
+	defer f.flushHistory()
+
+	started := time.Now()
+	var progress *scanProgress
+	if f.config.BatchMode {
+		f.debug("Scanning %s", f.describeScan(len(targets)))
+		progress = newScanProgress(len(targets))
+		progress.start()
 	}
 
-	// Validate API credits
-	if apiInfo != nil && apiInfo.QueryCredits <= 0 {
-		warnColor.Println("\n[!] No query credits available")
-		warnColor.Printf("[!] Shodan search URL: %s\n", f.generateShodanSearchURL(hash))
-		return nil
+	results := make([]*AnalysisResult, len(targets))
+	failed := 0
+	var firstErr error
+
+	f.scan(targets, hashOnly, func(i int, result *AnalysisResult, err error) {
+		results[i] = result
+		if progress != nil {
+			progress.update(err != nil)
+		}
+
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		f.streamResult(result, err)
+	})
+
+	if progress != nil {
+		progress.finish()
 	}
+	f.writeRunFiles(results, started)
 
-	// Search Shodan
-	infoColor.Printf("[*] Searching Shodan...\n")
-	results, err := f.searchShodan(hash)
-	if err != nil {
-		// If search fails, provide manual search URL
-		warnColor.Printf("\n[!] Shodan search failed: %v\n", err)
-		warnColor.Printf("[!] Try searching manually: %s\n", f.generateShodanSearchURL(hash))
-		return err
+	if !f.config.BatchMode && firstErr != nil {
+		return firstErr
 	}
 
-	if results.Total == 0 && apiInfo != nil && (apiInfo.Plan == "dev" || apiInfo.Plan == "free") {
-		warnColor.Println("\n[!] No results found. This might be due to API key limitations.")
-		warnColor.Printf("[!] Try searching manually: %s\n", f.generateShodanSearchURL(hash))
-		return nil
+	switch f.config.OutputFormat {
+	case "json", "yaml":
+		if err := f.outputBatch(results, f.config.OutputFormat); err != nil {
+			return err
+		}
 	}
 
-	successColor.Printf("[+] Found %d matches\n", results.Total)
+	if f.config.BatchMode {
+		progress.report()
+		infoColor.Printf("\n[*] Processed %d targets (%d succeeded, %d failed)\n",
+			len(targets), len(targets)-failed, failed)
+	}
+	if failed == len(targets) {
+		return fmt.Errorf("all %d targets failed", failed)
+	}
+	return nil
+}
 
-	// Save results if enabled
-	if f.config.SaveResults {
-		if err := f.saveResults(results, hash); err != nil {
-			warnColor.Printf("\n[!] Failed to save results: %v\n", err)
+// Output one finished result as soon as it is ready. Text, tables and
+// JSON Lines are streamed; JSON and YAML are written once all are done.
+func (f *FaviconFinder) streamResult(result *AnalysisResult, err error) {
+	if err != nil {
+		if f.config.BatchMode {
+			errorColor.Printf("[-] %s: %v\n", result.Target, err)
+		}
+		// Tables and JSON Lines keep a record for every target,
+		// including failures
+		switch f.config.OutputFormat {
+		case "csv", "tsv", "jsonl":
+			if err := f.outputResults(result, f.config.OutputFormat); err != nil {
+				errorColor.Printf("[-] Failed to output results: %v\n", err)
+			}
 		}
+		return
 	}
 
-	return f.outputResults(results, f.config.OutputFormat)
+	if f.config.OutputFormat != "json" && f.config.OutputFormat != "yaml" {
+		if err := f.outputResults(result, f.config.OutputFormat); err != nil {
+			errorColor.Printf("[-] Failed to output results: %v\n", err)
+		}
+	}
 }
 
-func main() {
-	var (
-		shodanKey    = flag.String("k", "", "Shodan API key")
-		hashOnly     = flag.Bool("hash", false, "Only calculate hash without Shodan search")
-		help         = flag.Bool("h", false, "Show help")
-		debug        = flag.Bool("debug", false, "Enable debug output")
-		outputFormat = flag.String("o", "text", "Output format (text, json, yaml)")
-		timeout      = flag.Duration("t", 5*time.Second, "Timeout for requests")
-		retryCount   = flag.Int("r", 3, "Number of retries for failed requests")
-		retryDelay   = flag.Duration("delay", 2*time.Second, "Delay between retries")
-		proxyURL     = flag.String("proxy", "", "Proxy URL (e.g., http://127.0.0.1:8080)")
-		noRedirect   = flag.Bool("no-redirect", false, "Disable following redirects")
-		customUA     = flag.String("ua", "", "Custom User-Agent string")
-		saveResults  = flag.Bool("save", false, "Save results to file")
-		noHistory    = flag.Bool("no-history", false, "Disable search history")
-	)
-
-	flag.Usage = func() {
-		fmt.Printf(banner, version)
-		fmt.Printf("\nUsage: favhash [options] <url>\n\n")
-		fmt.Printf("Options:\n")
-		flag.PrintDefaults()
-		fmt.Printf("\nExamples:\n")
-		fmt.Printf("  favhash -k YOUR_SHODAN_KEY example.com\n")
-		fmt.Printf("  favhash -hash example.com\n")
-		fmt.Printf("  favhash -k YOUR_SHODAN_KEY -o json -t 15s example.com\n")
-		fmt.Printf("  favhash -k YOUR_SHODAN_KEY -proxy http://127.0.0.1:8080 example.com\n")
-	}
-
-	flag.Parse()
-
-	if *help || flag.NArg() == 0 {
-		flag.Usage()
-		os.Exit(0)
-	}
-
-	if !*hashOnly && *shodanKey == "" {
-		errorColor.Println("[-] Error: Shodan API key is required for searching. Use -k flag to provide it.")
-		warnColor.Println("[!] Tip: Use -hash flag if you only want to calculate the hash.")
-		os.Exit(1)
+// Write the JSON Lines summary, report and STIX bundle of a finished run
+func (f *FaviconFinder) writeRunFiles(results []*AnalysisResult, started time.Time) {
+	if f.config.OutputFormat == "jsonl" {
+		if err := writeJSONLSummary(os.Stdout, results, started); err != nil {
+			errorColor.Printf("[-] Failed to output results: %v\n", err)
+		}
 	}
 
-	config := &Config{
-		UserAgent:      *customUA,
-		Timeout:        *timeout,
-		RetryCount:     *retryCount,
-		RetryDelay:     *retryDelay,
-		ProxyURL:       *proxyURL,
-		OutputFormat:   *outputFormat,
-		FollowRedirect: !*noRedirect,
-		Debug:          *debug,
-		SaveResults:    *saveResults,
-		NoHistory:      *noHistory,
+	if f.config.ReportFile != "" {
+		if err := f.writeReport(f.config.ReportFile, results); err != nil {
+			errorColor.Printf("[-] Failed to write report: %v\n", err)
+		} else {
+			successColor.Printf("\n[+] Report written to %s\n", f.config.ReportFile)
+		}
 	}
 
-	// Initialize random seed for user agent rotation
-	rand.Seed(time.Now().UnixNano())
+	if f.config.STIXFile != "" {
+		if err := f.writeSTIX(f.config.STIXFile, results); err != nil {
+			errorColor.Printf("[-] Failed to write STIX bundle: %v\n", err)
+		} else {
+			successColor.Printf("\n[+] STIX bundle written to %s\n", f.config.STIXFile)
+		}
+	}
+}
 
-	// Print banner
-	fmt.Printf(banner, version)
+func main() {
+	// Subcommands come first; anything else is the legacy flag form
+	var err error
+	switch {
+	case len(os.Args) > 1 && (os.Args[1] == "help" || os.Args[1] == "--help"):
+		err = runHelp(os.Args[2:])
+	case len(os.Args) > 1 && findCommand(os.Args[1]) != nil:
+		err = findCommand(os.Args[1]).run(os.Args[2:])
+	default:
+		err = runLegacy(os.Args[1:])
+	}
 
-	finder := NewFaviconFinder(*shodanKey, config)
-	if err := finder.analyze(flag.Arg(0), *hashOnly); err != nil {
+	if err != nil {
 		errorColor.Printf("[-] Error: %v\n", err)
 		os.Exit(1)
 	}
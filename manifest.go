@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const maxManifestSize = 1 << 20
+
+// Well-known manifest locations tried when the page does not link one
+var manifestPaths = []string{
+	"/manifest.json",
+	"/site.webmanifest",
+}
+
+// WebManifest is the subset of a web app manifest favhash cares about
+type WebManifest struct {
+	Name  string         `json:"name"`
+	Icons []ManifestIcon `json:"icons"`
+}
+
+type ManifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose"`
+}
+
+// Collect manifest icon candidates. Manifests linked from the page are used
+// when present, otherwise the well-known locations are tried.
+func (f *FaviconFinder) findManifestCandidates(targetURL string, doc *goquery.Document) []FaviconCandidate {
+	var manifestURLs []string
+	if doc != nil {
+		doc.Find("link[rel~='manifest']").Each(func(_ int, s *goquery.Selection) {
+			if href, exists := s.Attr("href"); exists {
+				if resolvedURL, err := f.resolveURL(targetURL, href); err == nil {
+					manifestURLs = append(manifestURLs, resolvedURL)
+				}
+			}
+		})
+	}
+
+	if len(manifestURLs) == 0 {
+		parsedURL, err := url.Parse(targetURL)
+		if err != nil {
+			return nil
+		}
+		for _, path := range manifestPaths {
+			manifestURLs = append(manifestURLs, fmt.Sprintf("%s://%s%s", parsedURL.Scheme, parsedURL.Host, path))
+		}
+	}
+
+	var candidates []FaviconCandidate
+	for _, manifestURL := range manifestURLs {
+		icons, err := f.fetchManifest(manifestURL)
+		if err != nil {
+			f.debug("Manifest %s: %v", manifestURL, err)
+			continue
+		}
+		candidates = append(candidates, icons...)
+	}
+
+	return candidates
+}
+
+// Fetch a manifest and turn its icons into candidates resolved against the
+// manifest's own location
+func (f *FaviconFinder) fetchManifest(manifestURL string) ([]FaviconCandidate, error) {
+	f.debug("Checking web app manifest: %s", manifestURL)
+
+	resp, err := f.makeRequest(manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("got status code %d", resp.StatusCode)
+	}
+
+	var manifest WebManifest
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+
+	// Resolve against the final location in case the manifest redirected
+	baseURL := resp.Request.URL.String()
+
+	var candidates []FaviconCandidate
+	for _, icon := range manifest.Icons {
+		if icon.Src == "" {
+			continue
+		}
+
+		resolvedURL, err := f.resolveURL(baseURL, icon.Src)
+		if err != nil {
+			continue
+		}
+
+		f.debug("Found manifest icon: %s (sizes=%s type=%s purpose=%s)", resolvedURL, icon.Sizes, icon.Type, icon.Purpose)
+		candidates = append(candidates, FaviconCandidate{
+			URL:     resolvedURL,
+			Source:  "manifest",
+			Sizes:   icon.Sizes,
+			Type:    icon.Type,
+			Purpose: icon.Purpose,
+		})
+	}
+
+	return candidates, nil
+}
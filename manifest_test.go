@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestFindManifestCandidates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/static/app.webmanifest":
+			fmt.Fprint(w, `{"name": "App", "icons": [
+				{"src": "icons/192.png", "sizes": "192x192", "type": "image/png"},
+				{"src": "/root.png", "purpose": "maskable"},
+				{"src": "../up.png"},
+				{"src": "https://cdn.example.com/icon.png"},
+				{"src": ""}
+			]}`)
+		case "/moved.json":
+			http.Redirect(w, r, "/assets/manifest.json", http.StatusFound)
+		case "/assets/manifest.json":
+			fmt.Fprint(w, `{"icons": [{"src": "icon.png"}]}`)
+		case "/site.webmanifest":
+			fmt.Fprint(w, `{"icons": [{"src": "/well-known.png"}]}`)
+		case "/broken.json":
+			fmt.Fprint(w, `{"icons": [`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{
+			name: "relative to manifest",
+			page: `<link rel="manifest" href="static/app.webmanifest">`,
+			want: []string{"/app/static/icons/192.png", "/root.png", "/app/up.png", "https://cdn.example.com/icon.png"},
+		},
+		{
+			name: "redirected manifest",
+			page: `<link rel="manifest" href="/moved.json">`,
+			want: []string{"/assets/icon.png"},
+		},
+		{
+			name: "well-known location",
+			page: `<title>No manifest link</title>`,
+			want: []string{"/well-known.png"},
+		},
+		{
+			name: "unparsable manifest",
+			page: `<link rel="manifest" href="/broken.json">`,
+		},
+	}
+
+	f := newTestFinder(t, &Config{FollowRedirect: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><head>" + tt.page + "</head></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			candidates := f.findManifestCandidates(server.URL+"/app/index.html", doc)
+			var got []string
+			for _, c := range candidates {
+				if c.Source != "manifest" {
+					t.Errorf("%s has source %q", c.URL, c.Source)
+				}
+				got = append(got, strings.TrimPrefix(c.URL, server.URL))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("candidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without a page only the well-known locations are tried
+	candidates := f.findManifestCandidates(server.URL+"/app/", nil)
+	if len(candidates) != 1 || candidates[0].URL != server.URL+"/well-known.png" {
+		t.Fatalf("well-known candidates without a page = %+v", candidates)
+	}
+	// Icon metadata is carried over from the manifest
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<link rel="manifest" href="/app/static/app.webmanifest">`))
+	candidates = f.findManifestCandidates(server.URL, doc)
+	if c := candidates[0]; c.Sizes != "192x192" || c.Type != "image/png" {
+		t.Errorf("first icon = %+v, want sizes 192x192 and type image/png", c)
+	}
+	if c := candidates[1]; c.Purpose != "maskable" {
+		t.Errorf("second icon = %+v, want purpose maskable", c)
+	}
+}
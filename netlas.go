@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const netlasPageSize = 20
+
+// netlasEngine searches Netlas HTTP responses by favicon SHA256
+type netlasEngine struct {
+	engineBase
+}
+
+type netlasUser struct {
+	Plan struct {
+		Name string `json:"name"`
+	} `json:"plan"`
+}
+
+type netlasCertName struct {
+	CommonName   []string `json:"common_name"`
+	Organization []string `json:"organization"`
+}
+
+// Format a Netlas certificate name as "CN=..., O=..."
+func (n netlasCertName) String() string {
+	name := make(map[string]string)
+	if len(n.CommonName) > 0 {
+		name["CN"] = n.CommonName[0]
+	}
+	if len(n.Organization) > 0 {
+		name["O"] = n.Organization[0]
+	}
+	return formatDN(name)
+}
+
+type netlasResponse struct {
+	IP     string   `json:"ip"`
+	Port   int      `json:"port"`
+	Prot4  string   `json:"prot4"`
+	Host   string   `json:"host"`
+	Domain []string `json:"domain"`
+	Geo    struct {
+		Country string `json:"country"`
+		City    string `json:"city"`
+	} `json:"geo"`
+	Whois struct {
+		ASN struct {
+			Number interface{} `json:"number"`
+			Name   string      `json:"name"`
+		} `json:"asn"`
+		Net struct {
+			Organization string `json:"organization"`
+		} `json:"net"`
+	} `json:"whois"`
+	HTTP struct {
+		Title   string                 `json:"title"`
+		Headers map[string]interface{} `json:"headers"`
+	} `json:"http"`
+	Certificate struct {
+		Subject           netlasCertName `json:"subject"`
+		Issuer            netlasCertName `json:"issuer"`
+		FingerprintSHA256 string         `json:"fingerprint_sha256"`
+	} `json:"certificate"`
+	CVE []struct {
+		Name string `json:"name"`
+	} `json:"cve"`
+	LastUpdated string `json:"last_updated"`
+}
+
+type netlasSearchResponse struct {
+	Items []struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"items"`
+}
+
+func (e *netlasEngine) get(path string, params url.Values, v interface{}) error {
+	reqURL := e.baseURL + path
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", e.key)
+	return e.f.fetchJSON(req, e.name, v)
+}
+
+func (e *netlasEngine) ValidateKey() error {
+	var user netlasUser
+	return e.get("/api/users/current/", nil, &user)
+}
+
+// Netlas reports the plan but not a simple remaining-request figure
+func (e *netlasEngine) Quota() (*EngineQuota, error) {
+	var user netlasUser
+	if err := e.get("/api/users/current/", nil, &user); err != nil {
+		return nil, err
+	}
+	return &EngineQuota{Plan: user.Plan.Name}, nil
+}
+
+func (e *netlasEngine) Query(fp *Fingerprint) string {
+	return fmt.Sprintf("http.favicon.hash_sha256:%s", fp.SHA256)
+}
+
+func (e *netlasEngine) ManualSearchURL(fp *Fingerprint) string {
+	return "https://app.netlas.io/responses/?q=" + url.QueryEscape(e.Query(fp))
+}
+
+func (e *netlasEngine) Count(fp *Fingerprint) (*HostCount, error) {
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := e.get("/api/responses_count/", url.Values{"q": {e.Query(fp)}}, &count); err != nil {
+		return nil, err
+	}
+	return &HostCount{Total: count.Count}, nil
+}
+
+// The search endpoint does not return a total, so paging stops at the
+// first short page
+func (e *netlasEngine) SearchHash(fp *Fingerprint) (*SearchResult, error) {
+	query := e.Query(fp)
+
+	return e.f.collectPages(netlasPageSize, func(page int) ([]Host, int, error) {
+		var resp netlasSearchResponse
+		params := url.Values{"q": {query}, "start": {strconv.Itoa((page - 1) * netlasPageSize)}}
+		if err := e.get("/api/responses/", params, &resp); err != nil {
+			return nil, 0, err
+		}
+
+		var hosts []Host
+		for _, item := range resp.Items {
+			var data netlasResponse
+			if err := json.Unmarshal(item.Data, &data); err != nil {
+				e.f.debug("Skipping undecodable Netlas response: %v", err)
+				continue
+			}
+
+			host := Host{
+				IP:             data.IP,
+				Port:           data.Port,
+				Transport:      data.Prot4,
+				Domains:        data.Domain,
+				Org:            data.Whois.Net.Organization,
+				ASN:            formatASN(data.Whois.ASN.Number),
+				ISP:            data.Whois.ASN.Name,
+				Country:        data.Geo.Country,
+				City:           data.Geo.City,
+				HTTPTitle:      data.HTTP.Title,
+				SSLSubject:     data.Certificate.Subject.String(),
+				SSLIssuer:      data.Certificate.Issuer.String(),
+				SSLFingerprint: data.Certificate.FingerprintSHA256,
+				LastUpdate:     data.LastUpdated,
+			}
+			host.HTTPServer = firstString(data.HTTP.Headers["server"])
+			for _, cve := range data.CVE {
+				host.Vulns = appendUnique(host.Vulns, cve.Name)
+			}
+			if data.Host != "" && data.Host != data.IP {
+				host.Hostnames = []string{data.Host}
+			}
+			e.f.attachRaw(&host, e.name, item.Data)
+			hosts = append(hosts, host)
+		}
+		return hosts, -1, nil
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNetlasSearch(t *testing.T) {
+	var starts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "key" || r.URL.Query().Get("q") != "http.favicon.hash_sha256:abc" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		start := r.URL.Query().Get("start")
+		starts = append(starts, start)
+
+		// No total comes back, so a full page is followed by a short one
+		count := netlasPageSize
+		if start != "0" {
+			count = 1
+		}
+		var items []string
+		for i := 0; i < count; i++ {
+			items = append(items, fmt.Sprintf(`{"data": {
+				"ip": "192.0.2.%d", "port": 443, "prot4": "tcp", "host": "app.example.com",
+				"domain": ["example.com"], "geo": {"country": "FR", "city": "Paris"},
+				"whois": {"asn": {"number": ["64504"], "name": "Example ASN"}, "net": {"organization": "Example SAS"}},
+				"http": {"title": "Sign in", "headers": {"server": ["Apache"]}},
+				"certificate": {"subject": {"common_name": ["app.example.com"], "organization": ["Example SAS"]},
+					"issuer": {"common_name": ["Example CA"]}, "fingerprint_sha256": "ff00"},
+				"cve": [{"name": "CVE-2024-0001"}, {"name": "CVE-2024-0001"}],
+				"last_updated": "2024-05-01"
+			}}`, i))
+		}
+		fmt.Fprintf(w, `{"items": [%s]}`, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"netlas"},
+		EngineKeys: map[string]string{"netlas": "key"},
+		EngineURLs: map[string]string{"netlas": server.URL},
+		Pages:      3,
+	})
+
+	result, err := f.engines[0].SearchHash(&Fingerprint{SHA256: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(starts, ",") != "0,"+strconv.Itoa(netlasPageSize) {
+		t.Errorf("fetched offsets %v, want 0 and %d", starts, netlasPageSize)
+	}
+	if result.Total != netlasPageSize+1 || len(result.Hosts) != netlasPageSize+1 || !result.Complete {
+		t.Errorf("total %d, %d hosts, complete %v; want the retrieved count as total",
+			result.Total, len(result.Hosts), result.Complete)
+	}
+
+	host := result.Hosts[0]
+	if host.ASN != "AS64504" || host.Org != "Example SAS" || host.ISP != "Example ASN" || host.HTTPServer != "Apache" {
+		t.Errorf("host = %+v", host)
+	}
+	if host.SSLSubject != "CN=app.example.com, O=Example SAS" || host.SSLIssuer != "CN=Example CA" || host.SSLFingerprint != "ff00" {
+		t.Errorf("certificate = %q, %q, %q", host.SSLSubject, host.SSLIssuer, host.SSLFingerprint)
+	}
+	if len(host.Vulns) != 1 || len(host.Hostnames) != 1 || host.Hostnames[0] != "app.example.com" {
+		t.Errorf("vulns = %v, hostnames = %v", host.Vulns, host.Hostnames)
+	}
+}
+
+func TestNetlasQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/users/current/":
+			fmt.Fprint(w, `{"plan": {"name": "Business"}}`)
+		case "/api/responses_count/":
+			fmt.Fprint(w, `{"count": 57}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"netlas"},
+		EngineKeys: map[string]string{"netlas": "key"},
+		EngineURLs: map[string]string{"netlas": server.URL},
+	})
+
+	quota, err := f.engines[0].Quota()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Plan != "Business" || quota.Unit != "" {
+		t.Errorf("quota = %+v, want only the Business plan", quota)
+	}
+
+	count, err := f.engines[0].(Counter).Count(&Fingerprint{SHA256: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Total != 57 {
+		t.Errorf("count = %d, want 57", count.Total)
+	}
+}
+
+func TestNetlasAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"detail": "Invalid API key"}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"netlas"},
+		EngineKeys: map[string]string{"netlas": "key"},
+		EngineURLs: map[string]string{"netlas": server.URL},
+	})
+
+	if _, err := f.engines[0].SearchHash(&Fingerprint{SHA256: "abc"}); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("SearchHash() error = %v, want status 401", err)
+	}
+	if _, err := f.engines[0].Quota(); err == nil {
+		t.Error("Quota() should fail")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+)
+
+// printer writes colored lines to one output stream. Results go to stdout
+// and everything else (banner, status, progress, debug) goes to stderr, so
+// favhash can sit in the middle of a pipeline.
+type printer struct {
+	*color.Color
+	stream *os.File
+	out    io.Writer
+}
+
+func newPrinter(stream *os.File, attrs ...color.Attribute) *printer {
+	return &printer{Color: color.New(attrs...), stream: stream, out: colorable.NewColorable(stream)}
+}
+
+func (p *printer) Printf(format string, a ...interface{}) {
+	p.Fprintf(p.out, format, a...)
+}
+
+func (p *printer) Println(a ...interface{}) {
+	p.Fprintln(p.out, a...)
+}
+
+// Writer returns the printer's stream, for uncolored output such as the banner
+func (p *printer) Writer() io.Writer {
+	return p.out
+}
+
+var (
+	infoColor    = newPrinter(os.Stderr, color.FgCyan)
+	successColor = newPrinter(os.Stderr, color.FgGreen)
+	errorColor   = newPrinter(os.Stderr, color.FgRed)
+	warnColor    = newPrinter(os.Stderr, color.FgYellow)
+	debugColor   = newPrinter(os.Stderr, color.FgHiCyan)
+	resultColor  = newPrinter(os.Stdout, color.FgHiMagenta)
+)
+
+// Check whether a stream is an interactive terminal
+func isTerminal(stream *os.File) bool {
+	fd := stream.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// Configure colors and verbosity. Colors are only used on terminals, and
+// never with -no-color or NO_COLOR set. In silent mode only results and
+// errors are written.
+func setupOutput(noColor, silent bool) {
+	noColor = noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+
+	for _, p := range []*printer{infoColor, successColor, errorColor, warnColor, debugColor, resultColor} {
+		if noColor || !isTerminal(p.stream) {
+			p.DisableColor()
+		} else {
+			p.EnableColor()
+		}
+	}
+
+	if silent {
+		for _, p := range []*printer{infoColor, successColor, warnColor, debugColor} {
+			p.out = io.Discard
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const defaultHostConcurrency = 4
+
+// hostLimiter caps the number of in-flight requests per host
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	if limit <= 0 {
+		limit = defaultHostConcurrency
+	}
+	return &hostLimiter{
+		limit: limit,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+func (l *hostLimiter) hostSlots(host string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots, ok := l.slots[host]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.slots[host] = slots
+	}
+	return slots
+}
+
+// Wait for a free slot on host. The returned function releases it.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	slots := l.hostSlots(host)
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-slots }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Read non-empty lines from a file, skipping # comments
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// Build the favicon path list from commonFaviconPaths and an optional
+// wordlist, which either extends or replaces the built-in paths
+func loadFaviconPaths(wordlist string, replace bool) ([]string, error) {
+	var paths []string
+	if !replace {
+		paths = append(paths, commonFaviconPaths...)
+	}
+
+	if wordlist != "" {
+		lines, err := readLines(wordlist)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read path wordlist: %v", err)
+		}
+		paths = append(paths, lines...)
+	}
+
+	seen := make(map[string]bool)
+	unique := paths[:0]
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		if !seen[path] {
+			seen[path] = true
+			unique = append(unique, path)
+		}
+	}
+
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("no favicon paths to probe")
+	}
+	return unique, nil
+}
+
+// Probe the favicon paths on the target host concurrently. Hits are
+// returned in path-list order. When stopOnFirst is set a hit only cancels
+// the probes ranked below it, so the best-ranked path always wins however
+// the workers are scheduled.
+func (f *FaviconFinder) probePaths(targetURL string, stopOnFirst bool) ([]string, error) {
+	candidates, err := commonPathCandidates(targetURL, f.faviconPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// One context per probe so a hit can cancel just the lower-ranked ones
+	probeCtx := make([]context.Context, len(candidates))
+	probeCancel := make([]context.CancelFunc, len(candidates))
+	for i := range candidates {
+		probeCtx[i], probeCancel[i] = context.WithCancel(ctx)
+	}
+
+	var mu sync.Mutex
+	best := len(candidates)
+	rankedBelowHit := func(i int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return i > best
+	}
+	hit := func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if i >= best {
+			return
+		}
+		for j := i + 1; j < best; j++ {
+			probeCancel[j]()
+		}
+		best = i
+	}
+
+	workers := f.config.HostConcurrency
+	if workers <= 0 {
+		workers = defaultHostConcurrency
+	}
+	if workers > len(candidates) {
+		workers = len(candidates)
+	}
+
+	// Each worker only writes the indexes it was handed
+	found := make([]bool, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if probeCtx[i].Err() != nil {
+					continue
+				}
+				f.debug("Trying path: %s", candidates[i].URL)
+				if f.validateFavicon(probeCtx[i], candidates[i].URL).Valid {
+					found[i] = true
+					if stopOnFirst {
+						hit(i)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range candidates {
+		if stopOnFirst && rankedBelowHit(i) {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var hits []string
+	for i, ok := range found {
+		if ok {
+			hits = append(hits, candidates[i].URL)
+		}
+	}
+	return hits, nil
+}
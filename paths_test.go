@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFinder(t *testing.T, config *Config) *FaviconFinder {
+	t.Helper()
+	config.NoHistory = true
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if len(config.Engines) == 0 {
+		config.Engines = []string{defaultEngine}
+	}
+	f, err := NewFaviconFinder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCheckCommonPathsPrefersRank(t *testing.T) {
+	// /favicon.ico answers last, yet it ranks first in commonFaviconPaths
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/favicon.ico":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("Content-Type", "image/x-icon")
+		case strings.HasSuffix(r.URL.Path, ".png"):
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{HostConcurrency: 8})
+	for run := 0; run < 3; run++ {
+		got, err := f.checkCommonPaths(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := server.URL + "/favicon.ico"; got != want {
+			t.Fatalf("run %d: checkCommonPaths() = %s, want %s", run, got, want)
+		}
+	}
+}
+
+func TestProbePathsOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".png") {
+			w.Header().Set("Content-Type", "image/png")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{HostConcurrency: 8})
+	hits, err := f.probePaths(server.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for _, path := range commonFaviconPaths {
+		if strings.HasSuffix(path, ".png") {
+			want = append(want, server.URL+path)
+		}
+	}
+	if strings.Join(hits, " ") != strings.Join(want, " ") {
+		t.Errorf("probePaths() = %v, want %v", hits, want)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Perceptual hashes are computed over an 8x8 grid, yielding 64 bits
+const phashSize = 8
+
+// Decode favicon bytes into an image
+func decodeFavicon(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if b := img.Bounds(); b.Dx() == 0 || b.Dy() == 0 {
+		return nil, fmt.Errorf("empty image")
+	}
+	return img, nil
+}
+
+// Downscale an image to a width x height grid of grayscale values using box
+// sampling. Transparent pixels are composited onto white so that icons with
+// an alpha channel hash the same as their flattened counterparts.
+func grayGrid(img image.Image, width, height int) []float64 {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	grid := make([]float64, width*height)
+
+	for gy := 0; gy < height; gy++ {
+		y0 := bounds.Min.Y + gy*srcH/height
+		y1 := bounds.Min.Y + (gy+1)*srcH/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for gx := 0; gx < width; gx++ {
+			x0 := bounds.Min.X + gx*srcW/width
+			x1 := bounds.Min.X + (gx+1)*srcW/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var sum float64
+			var count int
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, b, a := img.At(x, y).RGBA()
+					// RGBA returns alpha-premultiplied 16-bit values
+					lum := 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					lum += 0xffff - float64(a)
+					sum += lum / 0x101
+					count++
+				}
+			}
+			grid[gy*width+gx] = sum / float64(count)
+		}
+	}
+
+	return grid
+}
+
+// Calculate average hash (aHash)
+func averageHash(img image.Image) uint64 {
+	grid := grayGrid(img, phashSize, phashSize)
+
+	var mean float64
+	for _, v := range grid {
+		mean += v
+	}
+	mean /= float64(len(grid))
+
+	var hash uint64
+	for i, v := range grid {
+		if v > mean {
+			hash |= 1 << uint(len(grid)-1-i)
+		}
+	}
+	return hash
+}
+
+// Calculate difference hash (dHash)
+func differenceHash(img image.Image) uint64 {
+	grid := grayGrid(img, phashSize+1, phashSize)
+
+	var hash uint64
+	bit := phashSize*phashSize - 1
+	for y := 0; y < phashSize; y++ {
+		for x := 0; x < phashSize; x++ {
+			if grid[y*(phashSize+1)+x] > grid[y*(phashSize+1)+x+1] {
+				hash |= 1 << uint(bit)
+			}
+			bit--
+		}
+	}
+	return hash
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// reportData is everything a report template renders
+type reportData struct {
+	Version   string
+	Generated string
+	Targets   []reportTarget
+	Succeeded int
+	Failed    int
+	Hosts     int
+}
+
+type reportTarget struct {
+	*AnalysisResult
+	Image  string
+	Icons  []reportIcon
+	Links  []reportLink
+	Groups []reportGroup
+}
+
+type reportIcon struct {
+	IconResult
+	Image string
+}
+
+type reportLink struct {
+	Engine string
+	URL    string
+}
+
+// reportGroup holds the matched hosts sharing an organization and country
+type reportGroup struct {
+	Org     string
+	Country string
+	Hosts   []Host
+}
+
+// Pick the report format from the file extension
+func reportFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return "html", nil
+	case ".md", ".markdown":
+		return "markdown", nil
+	}
+	return "", fmt.Errorf("unsupported report file %q (use .html or .md)", path)
+}
+
+// Embed favicon bytes as a data URI so the report needs no external assets
+func faviconDataURI(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	mimeType := faviconMIMEType(data, "")
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// Group hosts by organization and country, largest groups first
+func groupHosts(hosts []Host) []reportGroup {
+	index := make(map[string]int)
+	var groups []reportGroup
+	for _, host := range hosts {
+		org := host.Org
+		if org == "" {
+			org = "Unknown"
+		}
+		country := host.Country
+		if country == "" {
+			country = "Unknown"
+		}
+
+		key := org + "\x00" + country
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, reportGroup{Org: org, Country: country})
+		}
+		groups[i].Hosts = append(groups[i].Hosts, host)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Hosts) != len(groups[j].Hosts) {
+			return len(groups[i].Hosts) > len(groups[j].Hosts)
+		}
+		if groups[i].Org != groups[j].Org {
+			return groups[i].Org < groups[j].Org
+		}
+		return groups[i].Country < groups[j].Country
+	})
+	return groups
+}
+
+func (f *FaviconFinder) reportData(results []*AnalysisResult) *reportData {
+	data := &reportData{
+		Version:   version,
+		Generated: time.Now().Format(time.RFC1123),
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if result.Error != "" {
+			data.Failed++
+		} else {
+			data.Succeeded++
+		}
+		data.Hosts += len(result.Hosts)
+
+		target := reportTarget{
+			AnalysisResult: result,
+			Image:          faviconDataURI(result.favicon),
+			Groups:         groupHosts(result.Hosts),
+		}
+		if len(result.Icons) > 1 {
+			for _, icon := range result.Icons {
+				target.Icons = append(target.Icons, reportIcon{IconResult: icon, Image: faviconDataURI(icon.data)})
+			}
+		}
+		if result.Fingerprint != nil {
+			for _, engine := range f.engines {
+				target.Links = append(target.Links, reportLink{
+					Engine: engineTitle(engine.Name()),
+					URL:    engine.ManualSearchURL(result.Fingerprint),
+				})
+			}
+		}
+		data.Targets = append(data.Targets, target)
+	}
+	return data
+}
+
+// Write an HTML or Markdown report of all results
+func (f *FaviconFinder) writeReport(path string, results []*AnalysisResult) error {
+	format, err := reportFormat(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating report: %v", err)
+	}
+	defer file.Close()
+
+	data := f.reportData(results)
+	if format == "html" {
+		err = renderHTMLReport(file, data)
+	} else {
+		err = renderMarkdownReport(file, data)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing report: %v", err)
+	}
+	return file.Close()
+}
+
+var reportFuncs = map[string]interface{}{
+	"join": strings.Join,
+	"location": func(h Host) string {
+		return joinNonEmpty(", ", h.City, h.Country)
+	},
+	"product": func(h Host) string {
+		return joinNonEmpty(" ", h.Product, h.Version)
+	},
+	"port": func(h Host) string {
+		if h.Port == 0 {
+			return ""
+		}
+		return joinNonEmpty("/", fmt.Sprint(h.Port), h.Transport)
+	},
+}
+
+func renderHTMLReport(out io.Writer, data *reportData) error {
+	funcs := htmltemplate.FuncMap{
+		// Data URIs are built from the favicon bytes, never from page input
+		"image": func(uri string) htmltemplate.URL { return htmltemplate.URL(uri) },
+	}
+	for name, fn := range reportFuncs {
+		funcs[name] = fn
+	}
+
+	tmpl, err := htmltemplate.New("report").Funcs(funcs).Parse(htmlReportTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, data)
+}
+
+// Escape text for a Markdown table cell or heading, so pipes and HTML in
+// scraped titles cannot break the layout
+func markdownCell(value string) string {
+	value = strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;", "\r", " ", "\n", " ").Replace(value)
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func renderMarkdownReport(out io.Writer, data *reportData) error {
+	funcs := template.FuncMap{"cell": markdownCell}
+	for name, fn := range reportFuncs {
+		funcs[name] = fn
+	}
+
+	tmpl, err := template.New("report").Funcs(funcs).Parse(markdownReportTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(out, data)
+}
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Favhash Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1100px; color: #222; padding: 0 1em; }
+h1 { border-bottom: 2px solid #444; padding-bottom: .3em; }
+h2 { margin-top: 2em; border-bottom: 1px solid #ccc; padding-bottom: .2em; word-break: break-all; }
+table { border-collapse: collapse; width: 100%; margin: .8em 0; font-size: .92em; }
+th, td { border: 1px solid #ddd; padding: .35em .6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+code { font-family: Menlo, Consolas, monospace; font-size: .9em; word-break: break-all; }
+.favicon { width: 64px; height: 64px; object-fit: contain; border: 1px solid #ddd; padding: 4px; image-rendering: pixelated; }
+.thumb { width: 32px; height: 32px; object-fit: contain; }
+.error { color: #b00020; }
+.muted { color: #777; }
+</style>
+</head>
+<body>
+<h1>Favhash Report</h1>
+<p class="muted">Generated {{.Generated}} by favhash v{{.Version}}</p>
+<table>
+<tr><th>Targets</th><td>{{len .Targets}}</td></tr>
+<tr><th>Succeeded</th><td>{{.Succeeded}}</td></tr>
+<tr><th>Failed</th><td>{{.Failed}}</td></tr>
+<tr><th>Matched hosts</th><td>{{.Hosts}}</td></tr>
+</table>
+{{range .Targets}}
+<h2>{{.Target}}</h2>
+{{if .Error}}<p class="error">Error: {{.Error}}</p>{{end}}
+{{if .Image}}<p><img class="favicon" src="{{image .Image}}" alt="favicon"></p>{{end}}
+{{if .Fingerprint}}
+<table>
+<tr><th>Favicon URL</th><td><code>{{.FaviconURL}}</code></td></tr>
+{{range .Products}}<tr><th>Identified</th><td>{{.}} ({{.MatchedOn}}){{range .References}}<br><a href="{{.}}">{{.}}</a>{{end}}</td></tr>
+{{end}}{{with .Fingerprint}}<tr><th>MMH3 ({{.HashProfile}})</th><td><code>{{.MMH3}}</code></td></tr>
+<tr><th>MD5</th><td><code>{{.MD5}}</code></td></tr>
+<tr><th>SHA1</th><td><code>{{.SHA1}}</code></td></tr>
+<tr><th>SHA256</th><td><code>{{.SHA256}}</code></td></tr>
+{{if .AHash}}<tr><th>aHash / dHash</th><td><code>{{.AHash}}</code> / <code>{{.DHash}}</code></td></tr>{{end}}
+<tr><th>Size</th><td>{{.Size}} bytes</td></tr>{{end}}
+</table>
+{{end}}
+{{if .Icons}}
+<h3>Favicons ({{len .Icons}} unique)</h3>
+<table>
+<tr><th></th><th>URL</th><th>Source</th><th>MIME Type</th><th>MMH3</th><th>MD5</th></tr>
+{{range .Icons}}<tr><td>{{if .Image}}<img class="thumb" src="{{image .Image}}" alt="">{{end}}</td><td><code>{{.URL}}</code></td><td>{{join .Sources ", "}}</td><td>{{.MIMEType}}</td><td><code>{{.Fingerprint.MMH3}}</code></td><td><code>{{.Fingerprint.MD5}}</code></td></tr>
+{{end}}</table>
+{{end}}
+{{if .Links}}
+<h3>Search Links</h3>
+<ul>
+{{range .Links}}<li>{{.Engine}}: <a href="{{.URL}}">{{.URL}}</a></li>
+{{end}}</ul>
+{{end}}
+{{if .Groups}}
+<h3>Matched Hosts ({{len .Hosts}})</h3>
+{{range .Groups}}
+<h4>{{.Org}} &mdash; {{.Country}} ({{len .Hosts}})</h4>
+<table>
+<tr><th>IP</th><th>Port</th><th>Hostnames</th><th>Location</th><th>Product</th><th>Title</th><th>Engines</th></tr>
+{{range .Hosts}}<tr><td><code>{{.IP}}</code></td><td>{{port .}}</td><td>{{join .Hostnames ", "}}</td><td>{{location .}}</td><td>{{product .}}</td><td>{{.HTTPTitle}}</td><td>{{join .Engines ", "}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+{{end}}
+</body>
+</html>
+`
+
+const markdownReportTemplate = `# Favhash Report
+
+Generated {{.Generated}} by favhash v{{.Version}}
+
+| Targets | Succeeded | Failed | Matched hosts |
+|---------|-----------|--------|---------------|
+| {{len .Targets}} | {{.Succeeded}} | {{.Failed}} | {{.Hosts}} |
+{{range .Targets}}
+## {{cell .Target}}
+{{if .Error}}
+**Error:** {{cell .Error}}
+{{end}}{{if .Image}}
+![favicon]({{.Image}})
+{{end}}{{if .Fingerprint}}
+| Field | Value |
+|-------|-------|
+| Favicon URL | {{cell .FaviconURL}} |
+{{range .Products}}| Identified | {{cell .String}} ({{.MatchedOn}}) |
+{{end}}{{with .Fingerprint}}| MMH3 ({{.HashProfile}}) | ` + "`{{.MMH3}}`" + ` |
+| MD5 | ` + "`{{.MD5}}`" + ` |
+| SHA1 | ` + "`{{.SHA1}}`" + ` |
+| SHA256 | ` + "`{{.SHA256}}`" + ` |
+{{if .AHash}}| aHash / dHash | ` + "`{{.AHash}}` / `{{.DHash}}`" + ` |
+{{end}}| Size | {{.Size}} bytes |
+{{end}}{{end}}{{if .Icons}}
+### Favicons ({{len .Icons}} unique)
+
+| Icon | URL | Source | MIME Type | MMH3 | MD5 |
+|------|-----|--------|-----------|------|-----|
+{{range .Icons}}| {{if .Image}}![icon]({{.Image}}){{end}} | {{cell .URL}} | {{cell (join .Sources ", ")}} | {{cell .MIMEType}} | {{.Fingerprint.MMH3}} | {{.Fingerprint.MD5}} |
+{{end}}{{end}}{{if .Links}}
+### Search Links
+
+{{range .Links}}- {{.Engine}}: <{{.URL}}>
+{{end}}{{end}}{{if .Groups}}
+### Matched Hosts ({{len .Hosts}})
+{{range .Groups}}
+#### {{cell .Org}} — {{cell .Country}} ({{len .Hosts}})
+
+| IP | Port | Hostnames | Location | Product | Title | Engines |
+|----|------|-----------|----------|---------|-------|---------|
+{{range .Hosts}}| {{.IP}} | {{cell (port .)}} | {{cell (join .Hostnames ", ")}} | {{cell (location .)}} | {{cell (product .)}} | {{cell .HTTPTitle}} | {{cell (join .Engines ", ")}} |
+{{end}}{{end}}{{end}}{{end}}`
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"report.html", "html"},
+		{"out/REPORT.HTM", "html"},
+		{"findings.md", "markdown"},
+		{"findings.markdown", "markdown"},
+		{"report.pdf", ""},
+		{"report", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := reportFormat(tt.path)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("reportFormat(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestGroupHosts(t *testing.T) {
+	groups := groupHosts([]Host{
+		{IP: "192.0.2.1", Org: "B Corp", Country: "DE"},
+		{IP: "192.0.2.2"},
+		{IP: "192.0.2.3", Org: "B Corp", Country: "DE"},
+		{IP: "192.0.2.4", Org: "A Corp", Country: "DE"},
+	})
+
+	var got []string
+	for _, group := range groups {
+		got = append(got, group.Org+"/"+group.Country)
+	}
+	// Largest group first, then by organization
+	want := "B Corp/DE A Corp/DE Unknown/Unknown"
+	if strings.Join(got, " ") != want {
+		t.Errorf("groups = %v, want %s", got, want)
+	}
+}
+
+// Results with page-supplied text that must not leak into the markup
+func reportResults() []*AnalysisResult {
+	return []*AnalysisResult{
+		{
+			Target:      "https://example.com",
+			FaviconURL:  "https://example.com/favicon.ico",
+			Fingerprint: &Fingerprint{MMH3: -123, MD5: "abc", HashProfile: "shodan", Size: 4},
+			Hosts: []Host{{
+				IP:        "192.0.2.1",
+				Port:      443,
+				Transport: "tcp",
+				Hostnames: []string{"<b>evil</b>.example.com"},
+				Org:       "Example Inc",
+				Country:   "US",
+				HTTPTitle: "<script>alert(1)</script> | Admin",
+				Engines:   []string{"shodan"},
+			}},
+		},
+		{Target: "https://down.example.com", Error: "connection refused"},
+	}
+}
+
+func TestWriteReportHTML(t *testing.T) {
+	f := newTestFinder(t, &Config{})
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := f.writeReport(path, reportResults()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+
+	for _, raw := range []string{"<script>alert(1)", "<b>evil</b>"} {
+		if strings.Contains(report, raw) {
+			t.Errorf("report contains unescaped %q", raw)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;alert(1)&lt;/script&gt; | Admin", "&lt;b&gt;evil&lt;/b&gt;.example.com"} {
+		if !strings.Contains(report, escaped) {
+			t.Errorf("report is missing escaped %q", escaped)
+		}
+	}
+	if !strings.Contains(report, `<p class="error">Error: connection refused</p>`) {
+		t.Error("report is missing the failed target")
+	}
+}
+
+func TestWriteReportMarkdown(t *testing.T) {
+	f := newTestFinder(t, &Config{})
+	path := filepath.Join(t.TempDir(), "report.md")
+	if err := f.writeReport(path, reportResults()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+
+	want := []string{
+		"| 2 | 1 | 1 | 1 |",
+		"| MMH3 (shodan) | `-123` |",
+		"#### Example Inc — US (1)",
+		"| IP | Port | Hostnames | Location | Product | Title | Engines |",
+		`| 192.0.2.1 | 443/tcp | &lt;b&gt;evil&lt;/b&gt;.example.com | US | - | &lt;script&gt;alert(1)&lt;/script&gt; \| Admin | shodan |`,
+		"**Error:** connection refused",
+	}
+	for _, line := range want {
+		if !strings.Contains(report, line+"\n") {
+			t.Errorf("report is missing line %q:\n%s", line, report)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	maxBackoff    = 30 * time.Second
+	maxRetryAfter = time.Minute
+)
+
+// cancelOnClose releases a per-attempt context and host slot once the body
+// has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// Check whether a transport error is worth retrying
+func isRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// Check whether a transport error means the host could not be reached at
+// all, so retrying with another method is pointless. Timeouts are not
+// included: a server that hangs on one method may still answer another.
+func isConnectionError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// Check whether a response status is worth retrying
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// Parse the Retry-After header (delay in seconds or HTTP date)
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// Exponential backoff with jitter for the given retry (0-based)
+func (f *FaviconFinder) backoff(retry int) time.Duration {
+	delay := f.config.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	for i := 0; i < retry && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	// Keep at least half the delay and randomize the rest
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Hide API keys before URLs end up in logs
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := parsed.Query()
+	for _, param := range []string{"key", "api-key", "apikey"} {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// Send a request, retrying timeouts, connection resets, 429 and 5xx
+// responses. attemptTimeout bounds each individual attempt when non-zero.
+func (f *FaviconFinder) do(req *http.Request, attemptTimeout time.Duration) (*http.Response, error) {
+	retries := f.config.RetryCount
+	if retries > maxRetries {
+		retries = maxRetries
+	}
+	if retries < 0 {
+		retries = 0
+	}
+
+	ctx := req.Context()
+	logURL := redactURL(req.URL.String())
+
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", f.userAgent.UserAgent(req.URL.Hostname()))
+	}
+	f.debug("%s %s using User-Agent: %s", req.Method, logURL, req.Header.Get("User-Agent"))
+
+	for attempt := 1; ; attempt++ {
+		if err := f.rateLimit.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		cancel := context.CancelFunc(func() {})
+		if attemptTimeout > 0 {
+			var attemptCtx context.Context
+			attemptCtx, cancel = context.WithTimeout(ctx, attemptTimeout)
+			attemptReq = req.Clone(attemptCtx)
+		}
+
+		release, err := f.hostLimit.acquire(ctx, req.URL.Host)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		cancelAttempt := cancel
+		cancel = func() {
+			cancelAttempt()
+			release()
+		}
+
+		resp, err := f.client.Do(attemptReq)
+		final := attempt > retries
+
+		if err != nil {
+			cancel()
+			if final || !isRetryableError(err) {
+				f.debug("%s %s failed after %d attempt(s): %v", req.Method, logURL, attempt, err)
+				return nil, err
+			}
+
+			wait := f.backoff(attempt - 1)
+			f.debug("%s %s attempt %d failed: %v (retrying in %s)", req.Method, logURL, attempt, err, wait.Round(time.Millisecond))
+			if !sleepContext(ctx, wait) {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+
+		if final || !isRetryableStatus(resp.StatusCode) {
+			f.debug("%s %s -> %d after %d attempt(s)", req.Method, logURL, resp.StatusCode, attempt)
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		wait := f.backoff(attempt - 1)
+		if resp.StatusCode == http.StatusTooManyRequests && wait < rateLimitWait {
+			wait = rateLimitWait
+		}
+		if after, ok := retryAfter(resp); ok {
+			if after > maxRetryAfter {
+				f.debug("%s %s asked to retry after %s, giving up after %d attempt(s)", req.Method, logURL, after, attempt)
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+				return resp, nil
+			}
+			wait = after
+		}
+
+		// Drain so the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		cancel()
+
+		f.debug("%s %s attempt %d returned %d (retrying in %s)", req.Method, logURL, attempt, resp.StatusCode, wait.Round(time.Millisecond))
+		if !sleepContext(ctx, wait) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Sleep unless the context is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency = 10
+	progressInterval   = 2 * time.Second
+)
+
+// rateLimiter spaces requests evenly to stay under a global rate
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// Create a limiter for rps requests per second, or nil for no limit
+func newRateLimiter(rps float64) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// Block until the next request slot is available
+func (r *rateLimiter) wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	slot := r.next
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	if !sleepContext(ctx, time.Until(slot)) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// scanProgress tracks finished targets and reports progress on stderr
+type scanProgress struct {
+	mu      sync.Mutex
+	total   int
+	done    int
+	failed  int
+	started time.Time
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func newScanProgress(total int) *scanProgress {
+	return &scanProgress{
+		total:   total,
+		started: time.Now(),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Report progress periodically until finish is called
+func (p *scanProgress) start() {
+	p.stopped.Add(1)
+	go func() {
+		defer p.stopped.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.report()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (p *scanProgress) update(failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	if failed {
+		p.failed++
+	}
+}
+
+func (p *scanProgress) report() {
+	p.mu.Lock()
+	done, failed, total := p.done, p.failed, p.total
+	elapsed := time.Since(p.started)
+	p.mu.Unlock()
+
+	eta := "unknown"
+	if done > 0 {
+		remaining := time.Duration(float64(elapsed) / float64(done) * float64(total-done))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	infoColor.Printf("[*] Progress: %d/%d done, %d failed, %d remaining, ETA %s\n",
+		done, total, failed, total-done, eta)
+}
+
+func (p *scanProgress) finish() {
+	close(p.stop)
+	p.stopped.Wait()
+}
+
+// Analyze targets with a pool of workers. handle is called from the calling
+// goroutine for every finished target, in completion order.
+func (f *FaviconFinder) scan(targets []string, hashOnly bool, handle func(index int, result *AnalysisResult, err error)) {
+	workers := f.config.Concurrency
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	if workers > len(targets) {
+		workers = len(targets)
+	}
+
+	type outcome struct {
+		index  int
+		result *AnalysisResult
+		err    error
+	}
+
+	jobs := make(chan int)
+	outcomes := make(chan outcome)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result, err := f.analyze(targets[i], hashOnly)
+				outcomes <- outcome{index: i, result: result, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for i := range targets {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	for o := range outcomes {
+		handle(o.index, o.result, o.err)
+	}
+}
+
+// Describe the scan settings in debug output
+func (f *FaviconFinder) describeScan(targets int) string {
+	rate := "unlimited"
+	if f.config.RateLimit > 0 {
+		rate = fmt.Sprintf("%.1f req/s", f.config.RateLimit)
+	}
+	return fmt.Sprintf("%d targets, %d workers, %d requests per host, %s",
+		targets, f.config.Concurrency, f.hostLimit.limit, rate)
+}
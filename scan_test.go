@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanKeepsTargetOrder(t *testing.T) {
+	ico := buildICO([]int{2}, buildDIB(solidPixels(2, color.NRGBA{A: 0xff}), 32, true))
+
+	// Earlier targets answer more slowly, so they finish last
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			w.Header().Set("Content-Type", "image/x-icon")
+			w.Write(ico)
+			return
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/site")); err == nil {
+			time.Sleep(time.Duration(5-n) * 40 * time.Millisecond)
+			fmt.Fprint(w, `<html><head><link rel="icon" href="/favicon.ico"></head></html>`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	var targets []string
+	for i := 0; i < 5; i++ {
+		targets = append(targets, fmt.Sprintf("%s/site%d", server.URL, i))
+	}
+
+	f := newTestFinder(t, &Config{Concurrency: 5})
+	results := make([]*AnalysisResult, len(targets))
+	var order []int
+	f.scan(targets, true, func(i int, result *AnalysisResult, err error) {
+		if err != nil {
+			t.Errorf("%s: %v", targets[i], err)
+		}
+		results[i] = result
+		order = append(order, i)
+	})
+
+	for i, result := range results {
+		if result == nil || result.Target != targets[i] {
+			t.Errorf("result %d = %+v, want target %s", i, result, targets[i])
+		}
+	}
+	if len(order) != len(targets) || order[0] == 0 {
+		t.Errorf("handled in order %v, want completion order with the slow first target last", order)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	var unlimited *rateLimiter
+	if newRateLimiter(0) != nil {
+		t.Error("a zero rate should mean no limiter")
+	}
+	if err := unlimited.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := newRateLimiter(50)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := limiter.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Six requests at 50/s need five 20ms gaps
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("6 requests at 50/s took %s, want at least 100ms", elapsed)
+	}
+
+	slow := newRateLimiter(0.5)
+	slow.wait(ctx)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := slow.wait(cancelled); err == nil {
+		t.Error("expected the wait to stop when the context is cancelled")
+	}
+}
+
+func TestHistoryFlush(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	f := newTestFinder(t, &Config{})
+	f.config.NoHistory = false
+	f.history = &HashHistory{}
+
+	saved := func() int {
+		data, err := os.ReadFile(historyFile)
+		if err != nil {
+			return 0
+		}
+		var history HashHistory
+		if err := json.Unmarshal(data, &history); err != nil {
+			t.Fatal(err)
+		}
+		return len(history.Hashes)
+	}
+
+	for i := 0; i < 3; i++ {
+		f.recordHistory(&AnalysisResult{Target: fmt.Sprintf("https://%d.example.com", i)}, time.Now())
+	}
+	if n := saved(); n != 0 {
+		t.Errorf("%d entries written before the flush, want none", n)
+	}
+	f.flushHistory()
+	if n := saved(); n != 3 {
+		t.Errorf("%d entries written by the flush, want 3", n)
+	}
+
+	// Long batches are saved as they go
+	for i := 0; i < historySaveEvery; i++ {
+		f.recordHistory(&AnalysisResult{Target: "https://batch.example.com"}, time.Now())
+	}
+	if n := saved(); n != 3+historySaveEvery {
+		t.Errorf("%d entries written during the batch, want %d", n, 3+historySaveEvery)
+	}
+}
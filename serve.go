@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"time"
+)
+
+// Default address of the serve command; loopback only unless asked
+const defaultListenAddr = "127.0.0.1:8080"
+
+// serveHashResponse is a target's analysis with the manual search URL of
+// each engine
+type serveHashResponse struct {
+	*AnalysisResult
+	SearchURLs map[string]string `json:"search_urls,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// Build the HTTP API around a finder
+func (f *FaviconFinder) serveMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": version})
+	})
+
+	mux.HandleFunc("/hash", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		target := r.URL.Query().Get("url")
+		if target == "" {
+			writeJSONError(w, http.StatusBadRequest, errors.New("missing url parameter"))
+			return
+		}
+
+		result, err := f.analyze(target, true)
+		f.flushHistory()
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, serveHashResponse{AnalysisResult: result})
+			return
+		}
+
+		response := serveHashResponse{AnalysisResult: result, SearchURLs: make(map[string]string)}
+		for _, engine := range f.engines {
+			response.SearchURLs[engine.Name()] = engine.ManualSearchURL(result.Fingerprint)
+		}
+		writeJSON(w, http.StatusOK, response)
+	})
+
+	mux.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		hash := r.URL.Query().Get("hash")
+		if hash == "" {
+			writeJSONError(w, http.StatusBadRequest, errors.New("missing hash parameter"))
+			return
+		}
+
+		result, err := f.lookup(hash)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+
+	return mux
+}
+
+// Flags of the serve subcommand
+func serveCommand(fs *flag.FlagSet) func(args []string) error {
+	a := &analysisFlags{}
+	listen := fs.String("listen", defaultListenAddr, "Address to listen on")
+	a.registerCommon(fs)
+	a.registerHTTP(fs)
+	a.registerDiscovery(fs)
+	a.registerEngines(fs, "Engines to build manual search URLs for, comma-separated")
+
+	return func(args []string) error {
+		setupOutput(a.noColor, false)
+
+		config, err := a.config(true)
+		if err != nil {
+			return err
+		}
+		// Results go to the HTTP clients; the terminal only logs progress
+		config.OutputFormat = "json"
+
+		f, err := NewFaviconFinder(config)
+		if err != nil {
+			return err
+		}
+
+		server := &http.Server{
+			Addr:              *listen,
+			Handler:           f.serveMux(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		successColor.Printf("[+] Listening on http://%s\n", *listen)
+		return server.ListenAndServe()
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestServeMux(t *testing.T) {
+	favicon := encodePNG(t, 16, color.NRGBA{G: 255, A: 255})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><link rel="icon" href="/icon.png"></head></html>`))
+		case "/icon.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(favicon)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	f := newTestFinder(t, &Config{Engines: []string{"shodan", "fofa"}})
+	api := httptest.NewServer(f.serveMux())
+	defer api.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		field  string
+	}{
+		{"health", "GET", "/health", http.StatusOK, "status"},
+		{"hash", "GET", "/hash?url=" + url.QueryEscape(site.URL), http.StatusOK, "search_urls"},
+		{"hash without url", "GET", "/hash", http.StatusBadRequest, "error"},
+		{"hash by post", "POST", "/hash?url=example.com", http.StatusMethodNotAllowed, "error"},
+		{"hash of a dead site", "GET", "/hash?url=" + url.QueryEscape(site.URL+"/missing/"), http.StatusBadGateway, "error"},
+		{"lookup", "GET", "/lookup?hash=-297069493", http.StatusOK, "hash_type"},
+		{"lookup without hash", "GET", "/lookup", http.StatusBadRequest, "error"},
+		{"lookup of a bad hash", "GET", "/lookup?hash=zzz", http.StatusBadRequest, "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, api.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d (%v)", resp.StatusCode, tt.status, body)
+			}
+			if resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %s", resp.Header.Get("Content-Type"))
+			}
+			if _, ok := body[tt.field]; !ok {
+				t.Errorf("response %v has no %s", body, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Shodan returns 100 matches per search page
+const shodanPageSize = 100
+
+// ShodanPagination is the saved state of a paginated search, used to resume
+// after an interruption or after hitting the credit floor
+type ShodanPagination struct {
+	Query        string            `json:"query"`
+	NextPage     int               `json:"next_page"`
+	Total        int               `json:"total"`
+	CreditsSpent int               `json:"credits_spent"`
+	Matches      []json.RawMessage `json:"matches"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Location of the pagination state for a hash
+func paginationFile(hash int32) string {
+	return filepath.Join(resultsDir, fmt.Sprintf("favhash_%d.pagination.json", hash))
+}
+
+func loadPagination(hash int32) (*ShodanPagination, error) {
+	data, err := os.ReadFile(paginationFile(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	var state ShodanPagination
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func savePagination(hash int32, state *ShodanPagination) error {
+	state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(paginationFile(hash), data, 0644)
+}
+
+// Check whether every page of the search has been fetched
+func (s *ShodanPagination) exhausted() bool {
+	return s.NextPage > 1 && (s.NextPage-1)*shodanPageSize >= s.Total
+}
+
+// shodanEngine searches Shodan by MMH3 hash. Pagination, resume and the
+// credit floor only apply to Shodan, which bills per result page.
+type shodanEngine struct {
+	engineBase
+
+	// Serialises searches per hash, so batch workers that hit the same
+	// favicon do not overwrite each other's pagination state
+	mu           sync.Mutex
+	hashSearches map[int32]*sync.Mutex
+}
+
+// Lock the pagination state of a hash. The returned function unlocks it.
+func (e *shodanEngine) lockHash(hash int32) func() {
+	e.mu.Lock()
+	if e.hashSearches == nil {
+		e.hashSearches = make(map[int32]*sync.Mutex)
+	}
+	lock, ok := e.hashSearches[hash]
+	if !ok {
+		lock = &sync.Mutex{}
+		e.hashSearches[hash] = lock
+	}
+	e.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// Check Shodan API key and get plan information
+func (e *shodanEngine) checkAPIKey() (*ShodanAPIInfo, error) {
+	f := e.f
+	f.debug("Checking Shodan API key status")
+	resp, err := f.makeRequest(fmt.Sprintf("%s/api-info?key=%s", e.baseURL, e.key))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("invalid API key (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var info ShodanAPIInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode API info: %v", err)
+	}
+
+	// Update API status
+	if f.apiStatus != nil {
+		f.apiStatus.LastCheck = time.Now()
+		f.apiStatus.IsValid = true
+		f.apiStatus.ErrorCount = 0
+		f.saveAPIStatus()
+	}
+
+	return &info, nil
+}
+
+func (e *shodanEngine) ValidateKey() error {
+	info, err := e.checkAPIKey()
+	if err != nil {
+		return err
+	}
+
+	e.f.mu.Lock()
+	e.f.apiInfo = info
+	e.f.mu.Unlock()
+	return nil
+}
+
+func (e *shodanEngine) Quota() (*EngineQuota, error) {
+	e.f.mu.Lock()
+	info := e.f.apiInfo
+	e.f.mu.Unlock()
+
+	if info == nil {
+		var err error
+		if info, err = e.checkAPIKey(); err != nil {
+			return nil, err
+		}
+	}
+	return &EngineQuota{Plan: info.Plan, Remaining: info.QueryCredits, Unit: "query credits"}, nil
+}
+
+func (e *shodanEngine) Query(fp *Fingerprint) string {
+	return fmt.Sprintf("http.favicon.hash:%d", fp.MMH3)
+}
+
+// Generate Shodan search URL for manual search
+func (e *shodanEngine) ManualSearchURL(fp *Fingerprint) string {
+	return "https://www.shodan.io/search?query=" + e.Query(fp)
+}
+
+func (e *shodanEngine) SearchHash(fp *Fingerprint) (*SearchResult, error) {
+	f := e.f
+	if !f.hasCredits() {
+		return nil, errNoCredits
+	}
+
+	results, err := e.search(fp.MMH3)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	apiInfo := f.apiInfo
+	f.mu.Unlock()
+	if results.Total == 0 && apiInfo != nil && (apiInfo.Plan == "dev" || apiInfo.Plan == "free") {
+		warnColor.Println("\n[!] No results found. This might be due to API key limitations.")
+	}
+
+	search := &SearchResult{
+		Total:        results.Total,
+		Pages:        results.Pages,
+		CreditsSpent: results.CreditsSpent,
+		Complete:     results.Complete,
+	}
+	for _, raw := range results.Matches {
+		var match ShodanMatch
+		if err := json.Unmarshal(raw, &match); err != nil {
+			f.debug("Skipping undecodable Shodan match: %v", err)
+			continue
+		}
+
+		host := Host{
+			IP:             match.IP,
+			Port:           match.Port,
+			Transport:      match.Transport,
+			Hostnames:      match.Hostnames,
+			Domains:        match.Domains,
+			Org:            match.Org,
+			ASN:            match.ASN,
+			ISP:            match.ISP,
+			Country:        match.Location.Country,
+			City:           match.Location.City,
+			Product:        match.Product,
+			Version:        match.Version,
+			HTTPTitle:      match.HTTP.Title,
+			HTTPServer:     match.HTTP.Server,
+			SSLSubject:     formatDN(match.SSL.Cert.Subject),
+			SSLIssuer:      formatDN(match.SSL.Cert.Issuer),
+			SSLFingerprint: match.SSL.Cert.Fingerprint.SHA256,
+			Vulns:          sortedKeys(match.Vulns),
+			BannerHash:     match.Hash,
+			LastUpdate:     match.LastUpdate,
+			Tags:           match.Tags,
+		}
+		if host.LastUpdate == "" {
+			host.LastUpdate = match.Timestamp
+		}
+		f.attachRaw(&host, e.name, raw)
+		search.Hosts = append(search.Hosts, host)
+	}
+	return search, nil
+}
+
+// Fetch one page of Shodan search results
+func (e *shodanEngine) searchPage(query string, page int) (*ShodanResponse, error) {
+	searchURL := fmt.Sprintf("%s/shodan/host/search?key=%s&query=%s&page=%d",
+		e.baseURL, e.key, url.QueryEscape(query), page)
+
+	resp, err := e.f.makeRequest(searchURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Shodan API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Shodan response: %v", err)
+	}
+
+	var result ShodanResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		// Show the start of the body, which is often an HTML error page
+		if len(body) > 1024 {
+			body = body[:1024]
+		}
+		return nil, fmt.Errorf("failed to parse Shodan response: %v (body: %s)", err, strings.TrimSpace(string(body)))
+	}
+
+	return &result, nil
+}
+
+// Search Shodan, walking result pages until -pages or -max-results is
+// reached, the results run out, or spending another credit would cross the
+// credit floor. Progress is saved after every page so it can be resumed.
+func (e *shodanEngine) search(hash int32) (*ShodanResponse, error) {
+	f := e.f
+	query := fmt.Sprintf("http.favicon.hash:%d", hash)
+
+	unlock := e.lockHash(hash)
+	defer unlock()
+
+	state := &ShodanPagination{Query: query, NextPage: 1}
+	if f.config.Resume {
+		if saved, err := loadPagination(hash); err == nil && saved.Query == query {
+			state = saved
+			infoColor.Printf("[*] Resuming Shodan search at page %d (%d matches already retrieved)\n",
+				state.NextPage, len(state.Matches))
+		} else {
+			f.debug("No pagination state to resume for %s", query)
+		}
+	}
+
+	pages := f.config.Pages
+	if pages <= 0 {
+		pages = 1
+	}
+	lastPage := state.NextPage + pages - 1
+	maxResults := f.config.MaxResults
+	creditsThisRun := 0
+	var searchErr error
+
+	for state.NextPage <= lastPage && !state.exhausted() {
+		if maxResults > 0 && len(state.Matches) >= maxResults {
+			break
+		}
+
+		if !f.reserveCredit() {
+			warnColor.Printf("\n[!] Stopping at page %d: query credits have reached the floor (%d)\n",
+				state.NextPage, f.config.CreditFloor)
+			break
+		}
+
+		f.debug("Fetching Shodan page %d for %s", state.NextPage, query)
+		page, err := e.searchPage(query, state.NextPage)
+		if err != nil {
+			// Shodan only bills pages it returned
+			f.refundCredit()
+			searchErr = err
+			break
+		}
+
+		state.Total = page.Total
+		state.Matches = append(state.Matches, page.Matches...)
+		state.CreditsSpent++
+		creditsThisRun++
+		state.NextPage++
+
+		if len(page.Matches) == 0 {
+			// Shodan stopped returning matches before the reported total
+			state.Total = len(state.Matches)
+			break
+		}
+
+		if err := savePagination(hash, state); err != nil {
+			f.debug("Failed to save pagination state: %v", err)
+		}
+	}
+
+	// Nothing retrieved at all: surface the error as before
+	if searchErr != nil && len(state.Matches) == 0 {
+		return nil, searchErr
+	}
+
+	complete := state.exhausted() || (maxResults > 0 && len(state.Matches) >= maxResults)
+	if complete {
+		os.Remove(paginationFile(hash))
+	} else if state.NextPage > 1 {
+		if err := savePagination(hash, state); err == nil {
+			remaining := (state.Total + shodanPageSize - 1) / shodanPageSize
+			warnColor.Printf("\n[!] Retrieved %d of %d matches (%d of %d pages); use -resume to continue\n",
+				len(state.Matches), state.Total, state.NextPage-1, remaining)
+		}
+	}
+	if searchErr != nil {
+		warnColor.Printf("\n[!] Shodan search stopped early: %v\n", searchErr)
+	}
+
+	f.mu.Lock()
+	if f.apiInfo != nil {
+		infoColor.Printf("[*] Query credits spent: %d (remaining: %d)\n", creditsThisRun, f.apiInfo.QueryCredits)
+	}
+	f.mu.Unlock()
+
+	matches := state.Matches
+	if maxResults > 0 && len(matches) > maxResults {
+		matches = matches[:maxResults]
+	}
+
+	return &ShodanResponse{
+		Matches:      matches,
+		Total:        state.Total,
+		Pages:        state.NextPage - 1,
+		CreditsSpent: state.CreditsSpent,
+		Complete:     complete,
+	}, nil
+}
+
+// Facets requested from the count endpoint, with the top values for each
+var shodanFacets = []string{"country", "org", "port", "product", "asn"}
+
+const shodanFacetLimit = 5
+
+// Count matches and facet distributions for a hash. The count endpoint does
+// not consume query credits, so it is safe to call before every search.
+func (e *shodanEngine) Count(fp *Fingerprint) (*HostCount, error) {
+	query := e.Query(fp)
+
+	facets := make([]string, len(shodanFacets))
+	for i, facet := range shodanFacets {
+		facets[i] = fmt.Sprintf("%s:%d", facet, shodanFacetLimit)
+	}
+
+	countURL := fmt.Sprintf("%s/shodan/host/count?key=%s&query=%s&facets=%s",
+		e.baseURL, e.key, url.QueryEscape(query), url.QueryEscape(strings.Join(facets, ",")))
+
+	resp, err := e.f.makeRequest(countURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Shodan API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var count HostCount
+	if err := json.NewDecoder(resp.Body).Decode(&count); err != nil {
+		return nil, fmt.Errorf("failed to parse Shodan count response: %v", err)
+	}
+
+	return &count, nil
+}
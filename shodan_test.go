@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestShodanSearchRefundsFailedPages(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Mkdir(resultsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			http.Error(w, "upstream error", http.StatusBadGateway)
+			return
+		}
+		matches := strings.TrimSuffix(strings.Repeat("{},", shodanPageSize), ",")
+		fmt.Fprintf(w, `{"matches": [%s], "total": 250}`, matches)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"shodan"},
+		EngineKeys: map[string]string{"shodan": "test"},
+		EngineURLs: map[string]string{"shodan": server.URL},
+		Pages:      3,
+		RetryCount: 0,
+	})
+	f.apiInfo = &ShodanAPIInfo{QueryCredits: 5}
+
+	engine := f.engines[0].(*shodanEngine)
+	result, err := engine.search(1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Matches) != shodanPageSize || result.CreditsSpent != 1 {
+		t.Errorf("got %d matches for %d credits, want %d for 1", len(result.Matches), result.CreditsSpent, shodanPageSize)
+	}
+	if f.apiInfo.QueryCredits != 4 {
+		t.Errorf("tracked credits = %d, want 4 after one billed page", f.apiInfo.QueryCredits)
+	}
+
+	// The partial search is saved for -resume
+	saved, err := loadPagination(1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.NextPage != 2 || len(saved.Matches) != shodanPageSize {
+		data, _ := json.Marshal(saved)
+		t.Errorf("saved pagination = %s, want next page 2 with %d matches", data, shodanPageSize)
+	}
+}
+
+func TestShodanSearchPageReportsBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body>Down for maintenance</body></html>")
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"shodan"},
+		EngineKeys: map[string]string{"shodan": "test"},
+		EngineURLs: map[string]string{"shodan": server.URL},
+	})
+	_, err := f.engines[0].(*shodanEngine).searchPage("http.favicon.hash:1", 1)
+	if err == nil || !strings.Contains(err.Error(), "Down for maintenance") {
+		t.Errorf("searchPage() error = %v, want it to include the response body", err)
+	}
+}
+
+func TestShodanCountOnly(t *testing.T) {
+	var facets string
+	searched := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/shodan/host/count":
+			facets = r.URL.Query().Get("facets")
+			fmt.Fprint(w, `{"total": 1234, "facets": {
+				"country": [{"count": 800, "value": "US"}, {"count": 434, "value": "DE"}],
+				"port": [{"count": 1000, "value": 443}, {"count": 234, "value": 8443}],
+				"org": []
+			}}`)
+		case "/shodan/host/search":
+			searched = true
+			fmt.Fprint(w, `{"matches": [], "total": 0}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"shodan"},
+		EngineKeys: map[string]string{"shodan": "test"},
+		EngineURLs: map[string]string{"shodan": server.URL},
+		CountOnly:  true,
+	})
+	search := f.searchEngine(f.engines[0], &Fingerprint{MMH3: 81586312, HashProfile: "shodan"})
+
+	if searched {
+		t.Error("count-only mode ran a paid search")
+	}
+	if facets != "country:5,org:5,port:5,product:5,asn:5" {
+		t.Errorf("requested facets %q", facets)
+	}
+	if search.Count == nil || search.Count.Total != 1234 || search.Total != 1234 {
+		t.Fatalf("count = %+v, total %d; want 1234", search.Count, search.Total)
+	}
+
+	country := search.Count.Facets["country"]
+	if len(country) != 2 || country[0].Value != "US" || country[0].Count != 800 {
+		t.Errorf("country facet = %+v", country)
+	}
+	// JSON numbers decode as float64
+	port := search.Count.Facets["port"]
+	if len(port) != 2 || port[0].Value != float64(443) || port[1].Count != 234 {
+		t.Errorf("port facet = %+v", port)
+	}
+	if _, ok := search.Count.Facets["org"]; !ok {
+		t.Error("empty org facet was dropped")
+	}
+}
+
+func TestShodanZeroCountSkipsSearch(t *testing.T) {
+	searched := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/shodan/host/search" {
+			searched = true
+		}
+		fmt.Fprint(w, `{"total": 0, "matches": []}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"shodan"},
+		EngineKeys: map[string]string{"shodan": "test"},
+		EngineURLs: map[string]string{"shodan": server.URL},
+	})
+	search := f.searchEngine(f.engines[0], &Fingerprint{MMH3: 1, HashProfile: "shodan"})
+	if searched || search.Error != "" {
+		t.Errorf("searched = %v, error %q; want the search skipped", searched, search.Error)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	stixSpecVersion = "2.1"
+	stixTimeFormat  = "2006-01-02T15:04:05.000Z"
+)
+
+// Namespace the STIX 2.1 spec defines for deterministic SCO identifiers
+var stixSCONamespace = [16]byte{
+	0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c,
+	0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7,
+}
+
+// Namespace for the indicator and relationship ids favhash derives, so
+// re-exporting the same fingerprint or host link reuses its id
+var stixFavhashNamespace = [16]byte{
+	0xa8, 0xfb, 0xf6, 0x21, 0x31, 0x38, 0x54, 0xfa,
+	0x9b, 0x6c, 0xdd, 0x13, 0x91, 0x51, 0x6c, 0x0a,
+}
+
+// The MMH3 favicon hash is not in the STIX hash-algorithm-ov vocabulary,
+// so patterns carry it under a custom key
+const stixMMH3HashKey = "x_mmh3"
+
+type stixBundle struct {
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Objects []*stixObject `json:"objects"`
+}
+
+// stixObject covers the indicators, observables and relationships favhash
+// exports; unused properties are omitted
+type stixObject struct {
+	Type        string `json:"type"`
+	SpecVersion string `json:"spec_version"`
+	ID          string `json:"id"`
+	Created     string `json:"created,omitempty"`
+	Modified    string `json:"modified,omitempty"`
+
+	// Indicator
+	Name               string                  `json:"name,omitempty"`
+	Description        string                  `json:"description,omitempty"`
+	IndicatorTypes     []string                `json:"indicator_types,omitempty"`
+	Pattern            string                  `json:"pattern,omitempty"`
+	PatternType        string                  `json:"pattern_type,omitempty"`
+	ValidFrom          string                  `json:"valid_from,omitempty"`
+	ExternalReferences []stixExternalReference `json:"external_references,omitempty"`
+	FaviconURL         string                  `json:"x_favhash_favicon_url,omitempty"`
+	MMH3               *int32                  `json:"x_favhash_mmh3,omitempty"`
+	HashProfile        string                  `json:"x_favhash_hash_profile,omitempty"`
+
+	// ipv4-addr, ipv6-addr and domain-name
+	Value string `json:"value,omitempty"`
+
+	// Relationship
+	RelationshipType string `json:"relationship_type,omitempty"`
+	SourceRef        string `json:"source_ref,omitempty"`
+	TargetRef        string `json:"target_ref,omitempty"`
+}
+
+type stixExternalReference struct {
+	SourceName string `json:"source_name"`
+	URL        string `json:"url,omitempty"`
+}
+
+// stixBuilder collects objects for one bundle, skipping duplicates so a
+// host or hash shared by several targets is exported once.
+//
+// An object's id never changes between exports, so neither may its created
+// time. Indicators are dated by the first time their hash was recorded in
+// the history, and relationships by the indicator they were found under;
+// only a hash missing from the history falls back to the export time.
+type stixBuilder struct {
+	now        time.Time
+	firstSeen  map[string]time.Time
+	bundle     *stixBundle
+	seen       map[string]bool
+	indicators map[string]*stixObject
+	relations  map[string]*stixObject
+}
+
+func newSTIXBuilder(now time.Time, history *HashHistory) *stixBuilder {
+	b := &stixBuilder{
+		now:        now,
+		firstSeen:  make(map[string]time.Time),
+		bundle:     &stixBundle{Type: "bundle", ID: "bundle--" + uuid4()},
+		seen:       make(map[string]bool),
+		indicators: make(map[string]*stixObject),
+		relations:  make(map[string]*stixObject),
+	}
+	if history != nil {
+		for _, entry := range history.Hashes {
+			if entry.Fingerprint == nil {
+				continue
+			}
+			seen, ok := b.firstSeen[entry.Fingerprint.MD5]
+			if !ok || entry.DateTime.Before(seen) {
+				b.firstSeen[entry.Fingerprint.MD5] = entry.DateTime
+			}
+		}
+	}
+	return b
+}
+
+// Creation time of the indicator for a fingerprint. Icons are not recorded
+// in the history on their own, so they take the time of the target's
+// favicon they were found with.
+func (b *stixBuilder) created(fps ...*Fingerprint) string {
+	for _, fp := range fps {
+		if seen, ok := b.firstSeen[fp.MD5]; ok {
+			return seen.UTC().Format(stixTimeFormat)
+		}
+	}
+	return b.now.UTC().Format(stixTimeFormat)
+}
+
+func (b *stixBuilder) add(obj *stixObject) {
+	if b.seen[obj.ID] {
+		return
+	}
+	b.seen[obj.ID] = true
+	b.bundle.Objects = append(b.bundle.Objects, obj)
+}
+
+// Add an Indicator for a fingerprint and return its id and creation time.
+// The pattern matches the favicon file by its standard hashes and by the
+// MMH3 value that search engines index; the id is derived from the pattern.
+func (b *stixBuilder) indicator(result *AnalysisResult, faviconURL string, fp *Fingerprint, engines []SearchEngine) (string, string) {
+	pattern := fmt.Sprintf("[file:hashes.MD5 = '%s' OR file:hashes.'SHA-1' = '%s' OR file:hashes.'SHA-256' = '%s' OR file:hashes.%s = '%d']",
+		fp.MD5, fp.SHA1, fp.SHA256, stixMMH3HashKey, fp.MMH3)
+	if obj, ok := b.indicators[pattern]; ok {
+		return obj.ID, obj.Created
+	}
+
+	mmh3 := fp.MMH3
+	created := b.created(fp, result.Fingerprint)
+	obj := &stixObject{
+		Type:           "indicator",
+		SpecVersion:    stixSpecVersion,
+		ID:             "indicator--" + uuid5(stixFavhashNamespace, pattern),
+		Created:        created,
+		Modified:       created,
+		Name:           fmt.Sprintf("Favicon hash %d", fp.MMH3),
+		Description:    fmt.Sprintf("Favicon served by %s (%s)", result.Target, faviconURL),
+		IndicatorTypes: []string{"unknown"},
+		Pattern:        pattern,
+		PatternType:    "stix",
+		ValidFrom:      created,
+		FaviconURL:     faviconURL,
+		MMH3:           &mmh3,
+		HashProfile:    fp.HashProfile,
+	}
+	for _, engine := range engines {
+		obj.ExternalReferences = append(obj.ExternalReferences, stixExternalReference{
+			SourceName: engineTitle(engine.Name()),
+			URL:        engine.ManualSearchURL(fp),
+		})
+	}
+
+	b.indicators[pattern] = obj
+	b.add(obj)
+	return obj.ID, created
+}
+
+// Add an ipv4-addr, ipv6-addr or domain-name observable and return its id
+func (b *stixBuilder) observable(objectType, value string) string {
+	// The value is the only ID contributing property of these objects
+	contributing, _ := json.Marshal(map[string]string{"value": value})
+	id := objectType + "--" + uuid5(stixSCONamespace, string(contributing))
+	b.add(&stixObject{Type: objectType, SpecVersion: stixSpecVersion, ID: id, Value: value})
+	return id
+}
+
+// Relate two objects; the id is derived from the type and both ends. A
+// relationship found under several indicators keeps the earliest time.
+func (b *stixBuilder) relationship(relationshipType, source, target, created string) {
+	key := relationshipType + " " + source + " " + target
+	if obj, ok := b.relations[key]; ok {
+		if created < obj.Created {
+			obj.Created, obj.Modified = created, created
+		}
+		return
+	}
+	obj := &stixObject{
+		Type:             "relationship",
+		SpecVersion:      stixSpecVersion,
+		ID:               "relationship--" + uuid5(stixFavhashNamespace, key),
+		Created:          created,
+		Modified:         created,
+		RelationshipType: relationshipType,
+		SourceRef:        source,
+		TargetRef:        target,
+	}
+	b.relations[key] = obj
+	b.add(obj)
+}
+
+// Add a matched host: its address and names, each related to the
+// indicator, with the names resolving to the address
+func (b *stixBuilder) host(indicator, created string, host Host) {
+	var address string
+	if ip := net.ParseIP(host.IP); ip != nil {
+		if ip.To4() != nil {
+			address = b.observable("ipv4-addr", ip.String())
+		} else {
+			address = b.observable("ipv6-addr", ip.String())
+		}
+		b.relationship("related-to", indicator, address, created)
+	}
+
+	var names []string
+	for _, name := range append(append([]string{}, host.Hostnames...), host.Domains...) {
+		names = appendUnique(names, strings.ToLower(strings.TrimSuffix(name, ".")))
+	}
+	for _, name := range names {
+		if name == "" || net.ParseIP(name) != nil {
+			continue
+		}
+		domain := b.observable("domain-name", name)
+		b.relationship("related-to", indicator, domain, created)
+		if address != "" {
+			b.relationship("resolves-to", domain, address, created)
+		}
+	}
+}
+
+// Build a STIX 2.1 bundle from the results of a run, exported at now
+func (f *FaviconFinder) stixBundle(results []*AnalysisResult, now time.Time) *stixBundle {
+	f.mu.Lock()
+	b := newSTIXBuilder(now, f.history)
+	f.mu.Unlock()
+
+	for _, result := range results {
+		if result == nil || result.Fingerprint == nil {
+			continue
+		}
+
+		indicator, created := b.indicator(result, result.FaviconURL, result.Fingerprint, f.engines)
+		for _, icon := range result.Icons {
+			b.indicator(result, icon.URL, icon.Fingerprint, f.engines)
+		}
+		for _, host := range result.Hosts {
+			b.host(indicator, created, host)
+		}
+	}
+	return b.bundle
+}
+
+// Write the run's indicators and matched hosts as a STIX 2.1 bundle
+func (f *FaviconFinder) writeSTIX(path string, results []*AnalysisResult) error {
+	data, err := json.MarshalIndent(f.stixBundle(results, time.Now()), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding STIX bundle: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing STIX bundle: %v", err)
+	}
+	return nil
+}
+
+// Random (version 4) UUID
+func uuid4() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u)
+}
+
+// Name-based (version 5, SHA-1) UUID
+func uuid5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u)
+}
+
+func formatUUID(u [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
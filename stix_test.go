@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUUID5(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{`{"value":"198.51.100.3"}`, "28bb3599-77cd-5a82-a950-b5bc3caf07c4"},
+		{`{"value":"example.com"}`, "bedb4899-d24b-5401-bc86-8f6b4cc18ec7"},
+	}
+
+	for _, tt := range tests {
+		if got := uuid5(stixSCONamespace, tt.name); got != tt.want {
+			t.Errorf("uuid5(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSTIXBundle(t *testing.T) {
+	profile, err := getHashProfile("shodan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, err := calculateFingerprint([]byte("favicon"), profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []*AnalysisResult{{
+		Target:      "https://example.com",
+		FaviconURL:  "https://example.com/favicon.ico",
+		Fingerprint: fp,
+		Hosts:       []Host{{IP: "198.51.100.3", Port: 443, Hostnames: []string{"Example.com."}}},
+	}}
+
+	f := newTestFinder(t, &Config{})
+	firstSeen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	f.history = &HashHistory{Hashes: []HashResult{
+		{URL: "https://example.com", Fingerprint: fp, DateTime: firstSeen.Add(time.Hour)},
+		{URL: "https://example.com", Fingerprint: fp, DateTime: firstSeen},
+	}}
+
+	// Ids are deterministic, so every property must be too
+	first := f.stixBundle(results, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	second := f.stixBundle(results, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	if len(first.Objects) != len(second.Objects) {
+		t.Fatalf("exports have %d and %d objects", len(first.Objects), len(second.Objects))
+	}
+
+	types := make(map[string]int)
+	var pattern string
+	for i, obj := range first.Objects {
+		types[obj.Type]++
+		if !reflect.DeepEqual(obj, second.Objects[i]) {
+			t.Errorf("%s changed between exports:\n%+v\n%+v", obj.Type, obj, second.Objects[i])
+		}
+		if obj.Created != "" && obj.Created != "2024-03-01T12:00:00.000Z" {
+			t.Errorf("%s created %s, want the first time the hash was seen", obj.Type, obj.Created)
+		}
+		if !strings.HasPrefix(obj.ID, obj.Type+"--") {
+			t.Errorf("id %s does not match type %s", obj.ID, obj.Type)
+		}
+		if obj.Type == "indicator" {
+			pattern = obj.Pattern
+		}
+	}
+	if first.ID == second.ID {
+		t.Error("bundle ids should be unique per export")
+	}
+
+	want := map[string]int{"indicator": 1, "ipv4-addr": 1, "domain-name": 1, "relationship": 3}
+	for objectType, count := range want {
+		if types[objectType] != count {
+			t.Errorf("got %d %s objects, want %d", types[objectType], objectType, count)
+		}
+	}
+
+	if !strings.Contains(pattern, "file:hashes.x_mmh3 = '1051234394'") {
+		t.Errorf("pattern %q does not carry the MMH3 under the custom hash key", pattern)
+	}
+	if strings.Contains(pattern, "hashes.MMH3") {
+		t.Errorf("pattern %q uses MMH3 as a standard hash algorithm", pattern)
+	}
+}
+
+func TestSTIXBundleWithoutHistory(t *testing.T) {
+	results := []*AnalysisResult{{
+		Target:      "https://example.com",
+		Fingerprint: &Fingerprint{MD5: "abc"},
+		Hosts:       []Host{{IP: "198.51.100.3"}},
+	}}
+
+	f := newTestFinder(t, &Config{})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.FixedZone("CET", 3600))
+	for _, obj := range f.stixBundle(results, now).Objects {
+		if obj.Created != "" && obj.Created != "2024-12-31T23:00:00.000Z" {
+			t.Errorf("%s created %s, want the export time in UTC", obj.Type, obj.Created)
+		}
+	}
+}
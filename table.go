@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Separator for multi-value cells such as hostnames
+const tableListSeparator = ";"
+
+// tableColumn is one column of -o csv/tsv output. host is nil for targets
+// without matches.
+type tableColumn struct {
+	name  string
+	value func(result *AnalysisResult, host *Host) string
+}
+
+func fingerprintColumn(name string, value func(fp *Fingerprint) string) tableColumn {
+	return tableColumn{name, func(r *AnalysisResult, _ *Host) string {
+		if r.Fingerprint == nil {
+			return ""
+		}
+		return value(r.Fingerprint)
+	}}
+}
+
+func hostColumn(name string, value func(h *Host) string) tableColumn {
+	return tableColumn{name, func(_ *AnalysisResult, h *Host) string {
+		if h == nil {
+			return ""
+		}
+		return value(h)
+	}}
+}
+
+// Columns in their default order. New columns are appended so the order
+// stays stable for existing pipelines.
+var tableColumns = []tableColumn{
+	{"target", func(r *AnalysisResult, _ *Host) string { return r.Target }},
+	{"variant", func(r *AnalysisResult, _ *Host) string { return r.Variant }},
+	{"favicon_url", func(r *AnalysisResult, _ *Host) string { return r.FaviconURL }},
+	fingerprintColumn("hash", func(fp *Fingerprint) string { return strconv.Itoa(int(fp.MMH3)) }),
+	fingerprintColumn("hash_profile", func(fp *Fingerprint) string { return fp.HashProfile }),
+	fingerprintColumn("md5", func(fp *Fingerprint) string { return fp.MD5 }),
+	fingerprintColumn("sha1", func(fp *Fingerprint) string { return fp.SHA1 }),
+	fingerprintColumn("sha256", func(fp *Fingerprint) string { return fp.SHA256 }),
+	hostColumn("ip", func(h *Host) string { return h.IP }),
+	hostColumn("port", func(h *Host) string {
+		if h.Port == 0 {
+			return ""
+		}
+		return strconv.Itoa(h.Port)
+	}),
+	hostColumn("transport", func(h *Host) string { return h.Transport }),
+	hostColumn("hostnames", func(h *Host) string { return strings.Join(h.Hostnames, tableListSeparator) }),
+	hostColumn("domains", func(h *Host) string { return strings.Join(h.Domains, tableListSeparator) }),
+	hostColumn("org", func(h *Host) string { return h.Org }),
+	hostColumn("asn", func(h *Host) string { return h.ASN }),
+	hostColumn("isp", func(h *Host) string { return h.ISP }),
+	hostColumn("country", func(h *Host) string { return h.Country }),
+	hostColumn("city", func(h *Host) string { return h.City }),
+	hostColumn("product", func(h *Host) string { return h.Product }),
+	hostColumn("version", func(h *Host) string { return h.Version }),
+	hostColumn("http_title", func(h *Host) string { return h.HTTPTitle }),
+	hostColumn("http_server", func(h *Host) string { return h.HTTPServer }),
+	hostColumn("ssl_subject", func(h *Host) string { return h.SSLSubject }),
+	hostColumn("ssl_issuer", func(h *Host) string { return h.SSLIssuer }),
+	hostColumn("ssl_fingerprint", func(h *Host) string { return h.SSLFingerprint }),
+	hostColumn("vulns", func(h *Host) string { return strings.Join(h.Vulns, tableListSeparator) }),
+	hostColumn("tags", func(h *Host) string { return strings.Join(h.Tags, tableListSeparator) }),
+	hostColumn("banner_hash", func(h *Host) string {
+		if h.BannerHash == 0 {
+			return ""
+		}
+		return strconv.FormatInt(h.BannerHash, 10)
+	}),
+	hostColumn("last_update", func(h *Host) string { return h.LastUpdate }),
+	hostColumn("engines", func(h *Host) string { return strings.Join(h.Engines, tableListSeparator) }),
+	{"error", func(r *AnalysisResult, _ *Host) string { return r.Error }},
+	{"products", func(r *AnalysisResult, _ *Host) string {
+		products := make([]string, len(r.Products))
+		for i, match := range r.Products {
+			products[i] = match.Product
+		}
+		return strings.Join(products, tableListSeparator)
+	}},
+}
+
+func tableColumnNames() []string {
+	names := make([]string, len(tableColumns))
+	for i, column := range tableColumns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// Select columns from a comma-separated -fields list, in the given order.
+// An empty list selects every column.
+func selectColumns(fields string) ([]tableColumn, error) {
+	if strings.TrimSpace(fields) == "" {
+		return tableColumns, nil
+	}
+
+	byName := make(map[string]tableColumn, len(tableColumns))
+	for _, column := range tableColumns {
+		byName[column.name] = column
+	}
+
+	var columns []tableColumn
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		column, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", name, strings.Join(tableColumnNames(), ", "))
+		}
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no fields selected")
+	}
+	return columns, nil
+}
+
+// tableWriter streams results as CSV or TSV rows: one row per matched host,
+// or a single row for a target without matches
+type tableWriter struct {
+	out     io.Writer
+	csv     *csv.Writer
+	columns []tableColumn
+	started bool
+}
+
+func newTableWriter(out io.Writer, format, fields string) (*tableWriter, error) {
+	columns, err := selectColumns(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tableWriter{out: out, columns: columns}
+	if format == "csv" {
+		t.csv = csv.NewWriter(out)
+	}
+	return t, nil
+}
+
+func (t *tableWriter) writeRow(cells []string) error {
+	if t.csv != nil {
+		if err := t.csv.Write(cells); err != nil {
+			return err
+		}
+		t.csv.Flush()
+		return t.csv.Error()
+	}
+
+	// TSV cells cannot contain tabs or newlines, so they are flattened
+	// rather than quoted to keep the output friendly to cut and awk
+	for i, cell := range cells {
+		cells[i] = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(cell)
+	}
+	_, err := fmt.Fprintln(t.out, strings.Join(cells, "\t"))
+	return err
+}
+
+func (t *tableWriter) row(result *AnalysisResult, host *Host) []string {
+	cells := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		cells[i] = column.value(result, host)
+	}
+	return cells
+}
+
+// Write the rows for one target, preceded by the header on first use
+func (t *tableWriter) writeResult(result *AnalysisResult) error {
+	if !t.started {
+		header := make([]string, len(t.columns))
+		for i, column := range t.columns {
+			header[i] = column.name
+		}
+		if err := t.writeRow(header); err != nil {
+			return err
+		}
+		t.started = true
+	}
+
+	if len(result.Hosts) == 0 {
+		return t.writeRow(t.row(result, nil))
+	}
+	for i := range result.Hosts {
+		if err := t.writeRow(t.row(result, &result.Hosts[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
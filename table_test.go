@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  string
+		want    string
+		wantErr string
+	}{
+		{"default", "", strings.Join(tableColumnNames(), ","), ""},
+		{"given order", " IP, hash ,port", "ip,hash,port", ""},
+		{"empty entries", "ip,,port,", "ip,port", ""},
+		{"unknown field", "ip,hostname", "", `unknown field "hostname"`},
+		{"nothing selected", ",", "", "no fields selected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := selectColumns(tt.fields)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("selectColumns(%q) error = %v, want %q", tt.fields, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, column := range columns {
+				names = append(names, column.name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("selectColumns(%q) = %s, want %s", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableWriter(t *testing.T) {
+	results := []*AnalysisResult{
+		{
+			Target:      "https://example.com",
+			Fingerprint: &Fingerprint{MMH3: -123},
+			Hosts: []Host{
+				{IP: "192.0.2.1", Port: 443, Hostnames: []string{"a.example.com", "b.example.com"}},
+				{IP: "192.0.2.2", HTTPTitle: "Tab\there, \"quoted\""},
+			},
+		},
+		{Target: "https://down.example.com", Error: "connection refused"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "target,hash,ip,port,hostnames,http_title,error\n" +
+			"https://example.com,-123,192.0.2.1,443,a.example.com;b.example.com,,\n" +
+			"https://example.com,-123,192.0.2.2,,,\"Tab\there, \"\"quoted\"\"\",\n" +
+			"https://down.example.com,,,,,,connection refused\n"},
+		// TSV flattens tabs in cells instead of quoting them
+		{"tsv", "target\thash\tip\tport\thostnames\thttp_title\terror\n" +
+			"https://example.com\t-123\t192.0.2.1\t443\ta.example.com;b.example.com\t\t\n" +
+			"https://example.com\t-123\t192.0.2.2\t\t\tTab here, \"quoted\"\t\n" +
+			"https://down.example.com\t\t\t\t\t\tconnection refused\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			writer, err := newTableWriter(&out, tt.format, "target,hash,ip,port,hostnames,http_title,error")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, result := range results {
+				if err := writer.writeResult(result); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if out.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// Largest CIDR or range expanded into individual targets
+const maxExpandedHosts = 1 << 16
+
+// Collect targets from positional arguments, a -l list file and stdin.
+// "-" as an argument reads stdin explicitly; stdin is also read when no
+// targets were given and it is not a terminal (e.g. subfinder | favhash).
+// Malformed CIDRs and ranges are reported and skipped.
+func loadTargets(args []string, listFile string) ([]string, error) {
+	var raw []string
+	readStdin := false
+
+	for _, arg := range args {
+		if arg == "-" {
+			readStdin = true
+			continue
+		}
+		raw = append(raw, arg)
+	}
+
+	if listFile != "" {
+		file, err := os.Open(listFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open target list: %v", err)
+		}
+		lines, err := readTargets(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read target list: %v", err)
+		}
+		raw = append(raw, lines...)
+	}
+
+	if readStdin || (len(raw) == 0 && stdinIsPiped()) {
+		lines, err := readTargets(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read targets from stdin: %v", err)
+		}
+		raw = append(raw, lines...)
+	}
+
+	var targets []string
+	seen := make(map[string]bool)
+	for _, target := range raw {
+		expanded, err := expandTarget(target)
+		if err != nil {
+			warnColor.Printf("[!] Skipping target: %v\n", err)
+			continue
+		}
+		for _, t := range expanded {
+			if !seen[t] {
+				seen[t] = true
+				targets = append(targets, t)
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// Check whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// Read one target per line, skipping blank lines and # comments
+func readTargets(r io.Reader) ([]string, error) {
+	var targets []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// Expand a CIDR block (10.0.0.0/24) or IP range (10.0.0.1-10.0.0.20 or
+// 10.0.0.1-20) into individual addresses. IPv6 addresses are bracketed so
+// they can be used as URL hosts. Anything else is returned as is.
+func expandTarget(target string) ([]string, error) {
+	if strings.Contains(target, "://") {
+		return []string{target}, nil
+	}
+
+	if addr, err := netip.ParseAddr(target); err == nil {
+		return []string{hostString(addr)}, nil
+	}
+
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		return expandPrefix(prefix)
+	}
+
+	if start, end, ok := strings.Cut(target, "-"); ok {
+		first, err := netip.ParseAddr(start)
+		if err != nil {
+			// Hostnames may legitimately contain dashes
+			return []string{target}, nil
+		}
+
+		last, err := netip.ParseAddr(end)
+		if err != nil && first.Is4() {
+			// Short form: 10.0.0.1-20 replaces the last octet
+			octets := first.As4()
+			last, err = netip.ParseAddr(fmt.Sprintf("%d.%d.%d.%s", octets[0], octets[1], octets[2], end))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP range %q", target)
+		}
+		return expandRange(first, last)
+	}
+
+	return []string{target}, nil
+}
+
+func expandPrefix(prefix netip.Prefix) ([]string, error) {
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("CIDR %s is too large (max %d addresses)", prefix, maxExpandedHosts)
+	}
+
+	var hosts []string
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		hosts = append(hosts, hostString(addr))
+	}
+
+	// Drop network and broadcast addresses for IPv4 blocks larger than /31
+	if prefix.Addr().Is4() && hostBits > 1 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts, nil
+}
+
+func expandRange(first, last netip.Addr) ([]string, error) {
+	if first.BitLen() != last.BitLen() || last.Less(first) {
+		return nil, fmt.Errorf("invalid IP range %s-%s", first, last)
+	}
+
+	var hosts []string
+	for addr := first; addr.IsValid() && !last.Less(addr); addr = addr.Next() {
+		if len(hosts) >= maxExpandedHosts {
+			return nil, fmt.Errorf("IP range %s-%s is too large (max %d addresses)", first, last, maxExpandedHosts)
+		}
+		hosts = append(hosts, hostString(addr))
+	}
+	return hosts, nil
+}
+
+// Format an address as a URL host
+func hostString(addr netip.Addr) string {
+	if addr.Is6() {
+		return "[" + addr.String() + "]"
+	}
+	return addr.String()
+}
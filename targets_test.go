@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    []string
+		wantErr bool
+	}{
+		{target: "example.com", want: []string{"example.com"}},
+		{target: "https://example.com/a-b", want: []string{"https://example.com/a-b"}},
+		{target: "my-host.example.com", want: []string{"my-host.example.com"}},
+		{target: "10.0.0.5", want: []string{"10.0.0.5"}},
+		{target: "10.0.0.0/30", want: []string{"10.0.0.1", "10.0.0.2"}},
+		{target: "10.0.0.7/30", want: []string{"10.0.0.5", "10.0.0.6"}},
+		{target: "10.0.0.0/31", want: []string{"10.0.0.0", "10.0.0.1"}},
+		{target: "10.0.0.9/32", want: []string{"10.0.0.9"}},
+		{target: "2001:db8::/127", want: []string{"[2001:db8::]", "[2001:db8::1]"}},
+		{target: "2001:db8::1-2001:db8::3", want: []string{"[2001:db8::1]", "[2001:db8::2]", "[2001:db8::3]"}},
+		{target: "2001:db8::1", want: []string{"[2001:db8::1]"}},
+		{target: "::ffff:10.0.0.1", want: []string{"[::ffff:10.0.0.1]"}},
+		{target: "10.0.0.1-10.0.0.3", want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{target: "10.0.0.254-10.0.1.1", want: []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+		{target: "10.0.0.1-3", want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{target: "10.0.0.5-10.0.0.5", want: []string{"10.0.0.5"}},
+		{target: "10.0.0.5-10.0.0.1", wantErr: true},
+		{target: "10.0.0.1-300", wantErr: true},
+		{target: "10.0.0.1-::1", wantErr: true},
+		{target: "10.0.0.0/8", wantErr: true},
+		{target: "10.0.0.0-10.2.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := expandTarget(tt.target)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expandTarget() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("expandTarget() = %v, want %v", got, tt.want)
+			}
+			// analyze prefixes a scheme, which must give a usable host
+			for _, host := range got {
+				if parsed, err := url.Parse("https://" + host); err != nil || parsed.Hostname() == "" {
+					t.Errorf("%s is not a valid URL host: %v", host, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTargets(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "targets.txt")
+	content := "# staging hosts\nexample.com\n\n  10.0.0.0/30  \n10.0.0.1-300\nexample.com\n2001:db8::/127\n"
+	if err := os.WriteFile(list, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadTargets([]string{"10.0.0.1", "example.org"}, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The malformed range is skipped, not fatal
+	want := []string{"10.0.0.1", "example.org", "example.com", "10.0.0.2", "[2001:db8::]", "[2001:db8::1]"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("loadTargets() = %v, want %v", got, want)
+	}
+
+	if _, err := loadTargets(nil, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected an error for a missing list file")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+)
+
+const defaultUserAgentMode = "random"
+
+// UserAgentStrategy picks the User-Agent header for a request to host
+type UserAgentStrategy interface {
+	UserAgent(host string) string
+}
+
+// Always send the same User-Agent
+type fixedUserAgent string
+
+func (ua fixedUserAgent) UserAgent(string) string {
+	return string(ua)
+}
+
+// Pick a random User-Agent for every request
+type randomUserAgent struct {
+	agents []string
+}
+
+func (ua *randomUserAgent) UserAgent(string) string {
+	return ua.agents[rand.Intn(len(ua.agents))]
+}
+
+// Cycle through the User-Agents in order
+type rotatingUserAgent struct {
+	agents []string
+	next   atomic.Uint64
+}
+
+func (ua *rotatingUserAgent) UserAgent(string) string {
+	n := ua.next.Add(1) - 1
+	return ua.agents[n%uint64(len(ua.agents))]
+}
+
+// Map every host to one User-Agent so a target always sees the same client
+type perHostUserAgent struct {
+	agents []string
+}
+
+func (ua *perHostUserAgent) UserAgent(host string) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(host)))
+	return ua.agents[h.Sum32()%uint32(len(ua.agents))]
+}
+
+// Build the User-Agent strategy from config. A custom -ua string always wins;
+// otherwise agents come from -ua-file or the built-in list.
+func newUserAgentStrategy(config *Config) (UserAgentStrategy, error) {
+	if config.UserAgent != "" {
+		return fixedUserAgent(config.UserAgent), nil
+	}
+
+	agents := userAgents
+	if config.UserAgentFile != "" {
+		loaded, err := loadUserAgents(config.UserAgentFile)
+		if err != nil {
+			return nil, err
+		}
+		agents = loaded
+	}
+
+	switch config.UserAgentMode {
+	case "", "random":
+		return &randomUserAgent{agents: agents}, nil
+	case "rotate":
+		return &rotatingUserAgent{agents: agents}, nil
+	case "host":
+		return &perHostUserAgent{agents: agents}, nil
+	default:
+		return nil, fmt.Errorf("unknown User-Agent mode %q (available: random, rotate, host)", config.UserAgentMode)
+	}
+}
+
+// Load User-Agents from a file, one per line. Blank lines and lines
+// starting with # are skipped.
+func loadUserAgents(path string) ([]string, error) {
+	agents, err := readLines(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read User-Agent file: %v", err)
+	}
+
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no User-Agents found in %s", path)
+	}
+	return agents, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUserAgentStrategies(t *testing.T) {
+	agents := []string{"agent-a", "agent-b", "agent-c"}
+
+	rotate := &rotatingUserAgent{agents: agents}
+	var got []string
+	for i := 0; i < 5; i++ {
+		got = append(got, rotate.UserAgent("example.com"))
+	}
+	if want := "agent-a agent-b agent-c agent-a agent-b"; strings.Join(got, " ") != want {
+		t.Errorf("rotate = %v, want %s", got, want)
+	}
+
+	host := &perHostUserAgent{agents: agents}
+	seen := make(map[string]bool)
+	for _, name := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"} {
+		first := host.UserAgent(name)
+		for i := 0; i < 3; i++ {
+			if again := host.UserAgent(name); again != first {
+				t.Errorf("host %s got %s, then %s", name, first, again)
+			}
+		}
+		if upper := host.UserAgent(strings.ToUpper(name)); upper != first {
+			t.Errorf("host matching is case-sensitive: %s vs %s", first, upper)
+		}
+		seen[first] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("every host got the same User-Agent: %v", seen)
+	}
+
+	random := &randomUserAgent{agents: agents}
+	for i := 0; i < 10; i++ {
+		if ua := random.UserAgent(""); !strings.HasPrefix(ua, "agent-") {
+			t.Fatalf("random picked %q", ua)
+		}
+	}
+}
+
+func TestNewUserAgentStrategy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "agents.txt")
+	if err := os.WriteFile(file, []byte("# office browsers\nagent-one\n\nagent-two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		want    []string
+		wantErr bool
+	}{
+		{name: "fixed wins", config: Config{UserAgent: "custom", UserAgentFile: file, UserAgentMode: "rotate"}, want: []string{"custom", "custom", "custom"}},
+		{name: "rotate through file", config: Config{UserAgentFile: file, UserAgentMode: "rotate"}, want: []string{"agent-one", "agent-two", "agent-one"}},
+		{name: "unknown mode", config: Config{UserAgentMode: "sticky"}, wantErr: true},
+		{name: "missing file", config: Config{UserAgentFile: filepath.Join(t.TempDir(), "missing.txt")}, wantErr: true},
+		{name: "empty file", config: Config{UserAgentFile: empty}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := newUserAgentStrategy(&tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for range tt.want {
+				got = append(got, strategy.UserAgent("example.com"))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("User-Agents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const sniffLength = 512
+
+// RejectReason explains why a favicon candidate failed validation
+type RejectReason string
+
+const (
+	RejectNone          RejectReason = ""
+	RejectRequestFailed RejectReason = "request_failed"
+	RejectStatus        RejectReason = "bad_status"
+	RejectEmpty         RejectReason = "empty_body"
+	RejectHTML          RejectReason = "html_body"
+	RejectUnknownFormat RejectReason = "unknown_format"
+	RejectDecode        RejectReason = "decode_failed"
+)
+
+// ValidationResult describes how a candidate was checked and the outcome
+type ValidationResult struct {
+	URL         string       `json:"url"`
+	Valid       bool         `json:"valid"`
+	Method      string       `json:"method"`
+	StatusCode  int          `json:"status_code,omitempty"`
+	ContentType string       `json:"content_type,omitempty"`
+	Format      string       `json:"format,omitempty"`
+	Reason      RejectReason `json:"reason,omitempty"`
+	Detail      string       `json:"detail,omitempty"`
+}
+
+func (v *ValidationResult) String() string {
+	if v.Valid {
+		return fmt.Sprintf("valid %s via %s (content-type %q)", v.Format, v.Method, v.ContentType)
+	}
+	if v.Detail != "" {
+		return fmt.Sprintf("rejected via %s: %s (%s)", v.Method, v.Reason, v.Detail)
+	}
+	return fmt.Sprintf("rejected via %s: %s", v.Method, v.Reason)
+}
+
+// MIME types for the image formats favhash can sniff
+var formatMIMETypes = map[string]string{
+	"ico":  "image/x-icon",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"jpeg": "image/jpeg",
+	"svg":  "image/svg+xml",
+	"webp": "image/webp",
+	"bmp":  "image/bmp",
+}
+
+// Identify an image format from its leading bytes, or "" if unknown
+func sniffImageFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x00\x00\x01\x00")),
+		bytes.HasPrefix(data, []byte("\x00\x00\x02\x00")):
+		return "ico"
+	case bytes.HasPrefix(data, []byte(pngSignature)):
+		return "png"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return "gif"
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "jpeg"
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "webp"
+	case isBMP(data):
+		return "bmp"
+	}
+
+	if isSVG(data) {
+		return "svg"
+	}
+	return ""
+}
+
+// Check for a BMP file header whose file size leaves room for one of the
+// known DIB headers (core, info, v2/v3, v4, v5), so text starting "BM" is
+// not taken for a bitmap
+func isBMP(data []byte) bool {
+	if len(data) < bmpFileHeader+4 || !bytes.HasPrefix(data, []byte(bmpMagic)) {
+		return false
+	}
+
+	dibSize := binary.LittleEndian.Uint32(data[bmpFileHeader : bmpFileHeader+4])
+	switch dibSize {
+	case 12, 40, 56, 108, 124:
+	default:
+		return false
+	}
+
+	fileSize := binary.LittleEndian.Uint32(data[2:6])
+	return fileSize >= bmpFileHeader+dibSize
+}
+
+// Check for an SVG document, allowing an XML prolog, comments and doctype
+func isSVG(data []byte) bool {
+	text := strings.ToLower(strings.TrimLeft(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), " \t\r\n"))
+	if strings.HasPrefix(text, "<svg") {
+		return true
+	}
+	if strings.HasPrefix(text, "<?xml") || strings.HasPrefix(text, "<!--") || strings.HasPrefix(text, "<!doctype svg") {
+		return strings.Contains(text, "<svg") && !strings.Contains(text, "<html")
+	}
+	return false
+}
+
+// Check for an HTML page, which is what soft-404s usually return
+func looksLikeHTML(data []byte) bool {
+	text := strings.ToLower(strings.TrimLeft(string(data), " \t\r\n\xef\xbb\xbf"))
+	for _, marker := range []string{"<!doctype html", "<html", "<head", "<body", "<script", "<title"} {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// MIME type for favicon bytes, preferring the sniffed format over a
+// missing or generic Content-Type
+func faviconMIMEType(data []byte, contentType string) string {
+	mimeType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mimeType, "image/") {
+		return mimeType
+	}
+	if format := sniffImageFormat(data); format != "" {
+		return formatMIMETypes[format]
+	}
+	return mimeType
+}
+
+// Validate a favicon URL. A HEAD request with an explicit image type is
+// trusted; anything else (HEAD refused, generic or missing type, text/html)
+// falls back to a ranged GET whose first bytes are sniffed, as does a HEAD
+// that times out. Hosts that fail DNS or refuse the connection are
+// rejected without the GET.
+func (f *FaviconFinder) validateFavicon(ctx context.Context, faviconURL string) *ValidationResult {
+	if isDataURI(faviconURL) {
+		return f.validateInline(faviconURL)
+	}
+
+	result := &ValidationResult{URL: faviconURL, Method: "HEAD"}
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", faviconURL, nil)
+	if err != nil {
+		result.Reason = RejectRequestFailed
+		result.Detail = err.Error()
+		return result
+	}
+
+	// Use shorter timeout for each validation attempt
+	resp, err := f.do(req, validationTimeout)
+	if err != nil {
+		// A GET would only repeat the same retries against a dead host,
+		// or run after the probe was cancelled
+		if isConnectionError(err) || ctx.Err() != nil {
+			result.Reason = RejectRequestFailed
+			result.Detail = err.Error()
+			f.debug("Favicon %s %s", faviconURL, result)
+			return result
+		}
+		f.debug("HEAD %s failed, falling back to GET: %v", faviconURL, err)
+		return f.sniffFavicon(ctx, faviconURL)
+	}
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.ContentType = resp.Header.Get("Content-Type")
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		result.Reason = RejectStatus
+		f.debug("Favicon %s %s", faviconURL, result)
+		return result
+	case resp.StatusCode != 200:
+		f.debug("HEAD %s returned %d, falling back to GET", faviconURL, resp.StatusCode)
+		return f.sniffFavicon(ctx, faviconURL)
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(result.ContentType)
+	if strings.HasPrefix(mimeType, "image/") && isValidFaviconType(mimeType) {
+		result.Valid = true
+		result.Format = strings.TrimPrefix(mimeType, "image/")
+		f.debug("Valid favicon found: type=%s", result.ContentType)
+		return result
+	}
+
+	f.debug("HEAD %s returned content type %q, sniffing content", faviconURL, result.ContentType)
+	return f.sniffFavicon(ctx, faviconURL)
+}
+
+// Fetch the first bytes of a candidate and identify it by magic bytes
+func (f *FaviconFinder) sniffFavicon(ctx context.Context, faviconURL string) *ValidationResult {
+	result := &ValidationResult{URL: faviconURL, Method: "GET"}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", faviconURL, nil)
+	if err != nil {
+		result.Reason = RejectRequestFailed
+		result.Detail = err.Error()
+		return result
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", sniffLength-1))
+	req.Header.Set("Accept", "image/avif,image/webp,image/*,*/*;q=0.8")
+
+	resp, err := f.do(req, validationTimeout)
+	if err != nil {
+		result.Reason = RejectRequestFailed
+		result.Detail = err.Error()
+		f.debug("Favicon %s %s", faviconURL, result)
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.ContentType = resp.Header.Get("Content-Type")
+
+	// Servers that ignore Range answer 200 with the full body
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		result.Reason = RejectStatus
+		f.debug("Favicon %s %s", faviconURL, result)
+		return result
+	}
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, sniffLength))
+	if err != nil && len(head) == 0 {
+		result.Reason = RejectRequestFailed
+		result.Detail = err.Error()
+		f.debug("Favicon %s %s", faviconURL, result)
+		return result
+	}
+
+	switch format := sniffImageFormat(head); {
+	case len(head) == 0:
+		result.Reason = RejectEmpty
+	case format != "":
+		result.Valid = true
+		result.Format = format
+		f.debug("Valid favicon found: %s", result)
+		return result
+	case looksLikeHTML(head):
+		result.Reason = RejectHTML
+		result.Detail = fmt.Sprintf("HTML page served with status %d", resp.StatusCode)
+	default:
+		result.Reason = RejectUnknownFormat
+	}
+
+	f.debug("Favicon %s %s", faviconURL, result)
+	return result
+}
+
+// Validate an inline data: URI favicon by decoding and sniffing its bytes
+func (f *FaviconFinder) validateInline(faviconURL string) *ValidationResult {
+	result := &ValidationResult{URL: faviconURL, Method: "inline"}
+
+	data, mediaType, err := decodeDataURI(faviconURL)
+	result.ContentType = mediaType
+	switch format := sniffImageFormat(data); {
+	case err != nil:
+		result.Reason = RejectDecode
+		result.Detail = err.Error()
+	case len(data) == 0:
+		result.Reason = RejectEmpty
+	case format != "":
+		result.Valid = true
+		result.Format = format
+	default:
+		result.Reason = RejectUnknownFormat
+	}
+	f.debug("Favicon %s %s", displayURL(faviconURL), result)
+	return result
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"image/color"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSniffImageFormat(t *testing.T) {
+	bmp := buildBMP(solidPixels(2, color.NRGBA{A: 0xff}))
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"ico", []byte("\x00\x00\x01\x00\x01\x00"), "ico"},
+		{"cur", []byte("\x00\x00\x02\x00\x01\x00"), "ico"},
+		{"png", []byte(pngSignature + "\x00\x00\x00\x0dIHDR"), "png"},
+		{"gif87a", []byte("GIF87a\x01\x00"), "gif"},
+		{"gif89a", []byte("GIF89a\x01\x00"), "gif"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), "jpeg"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "webp"},
+		{"bmp", bmp, "bmp"},
+		{"bmp header only", bmp[:bmpFileHeader+4], "bmp"},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "svg"},
+		{"svg with prolog", []byte(`<?xml version="1.0"?><svg></svg>`), "svg"},
+		{"svg with bom and whitespace", []byte("\xef\xbb\xbf\n  <svg></svg>"), "svg"},
+		{"text starting BM", []byte("BMW dealers near you, find one today"), ""},
+		{"bmp with bad dib size", append([]byte("BM\x46\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"), 0x29, 0, 0, 0), ""},
+		{"bmp with short file size", append([]byte("BM\x10\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"), 0x28, 0, 0, 0), ""},
+		{"html", []byte("<!DOCTYPE html><html><body>Not found</body></html>"), ""},
+		{"xhtml with svg", []byte(`<?xml version="1.0"?><html><svg></svg></html>`), ""},
+		{"riff without webp", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), ""},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffImageFormat(tt.data); got != tt.want {
+				t.Errorf("sniffImageFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeHTML(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{"<!doctype html><title>404</title>", true},
+		{"  \n<HTML><BODY>gone</BODY></HTML>", true},
+		{"<script>location='/'</script>", true},
+		{"plain text error", false},
+		{"\x00\x00\x01\x00", false},
+	}
+
+	for _, tt := range tests {
+		if got := looksLikeHTML([]byte(tt.data)); got != tt.want {
+			t.Errorf("looksLikeHTML(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestValidateFavicon(t *testing.T) {
+	bmp := buildBMP(solidPixels(2, color.NRGBA{A: 0xff}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/typed.ico":
+			w.Header().Set("Content-Type", "image/x-icon")
+		case "/no-head.bmp":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(bmp)
+		case "/soft-404.ico":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>Page not found</body></html>"))
+		case "/bm-text.bmp":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("BMW owners club, page moved"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadURL := "http://" + listener.Addr().String() + "/favicon.ico"
+	listener.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		valid  bool
+		method string
+		reason RejectReason
+	}{
+		{"image content type", server.URL + "/typed.ico", true, "HEAD", RejectNone},
+		{"head refused", server.URL + "/no-head.bmp", true, "GET", RejectNone},
+		{"soft 404", server.URL + "/soft-404.ico", false, "GET", RejectHTML},
+		{"text starting BM", server.URL + "/bm-text.bmp", false, "GET", RejectUnknownFormat},
+		{"not found", server.URL + "/missing.ico", false, "HEAD", RejectStatus},
+		{"unreachable host skips GET", deadURL, false, "HEAD", RejectRequestFailed},
+	}
+
+	f := newTestFinder(t, &Config{RetryCount: 1})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := f.validateFavicon(context.Background(), tt.url)
+			if result.Valid != tt.valid || result.Method != tt.method || result.Reason != tt.reason {
+				t.Errorf("validateFavicon() = valid %v, method %s, reason %q; want %v, %s, %q",
+					result.Valid, result.Method, result.Reason, tt.valid, tt.method, tt.reason)
+			}
+		})
+	}
+}
+
+func TestValidateFaviconHeadTimeout(t *testing.T) {
+	ico := buildICO([]int{2}, buildDIB(solidPixels(2, color.NRGBA{A: 0xff}), 32, true))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// Hang until the client gives up
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(ico)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{Timeout: 300 * time.Millisecond})
+	result := f.validateFavicon(context.Background(), server.URL+"/favicon.ico")
+	if !result.Valid || result.Method != "GET" {
+		t.Errorf("validateFavicon() = valid %v, method %s, reason %q (%s); want a valid GET fallback",
+			result.Valid, result.Method, result.Reason, result.Detail)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// VariantResult records what a single host variant served
+type VariantResult struct {
+	URL         string       `json:"url"`
+	FaviconURL  string       `json:"favicon_url,omitempty"`
+	Fingerprint *Fingerprint `json:"fingerprint,omitempty"`
+	UserAgent   string       `json:"user_agent,omitempty"`
+	Icons       []IconResult `json:"icons,omitempty"`
+	Error       string       `json:"error,omitempty"`
+
+	// Favicon bytes, kept for reports
+	favicon []byte
+}
+
+// Build the list of URLs to probe for a target. The exact target always
+// comes first; with extended probing the www/apex and http/https variants
+// follow.
+func hostVariants(targetURL string, extended bool) ([]string, error) {
+	parsed, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("invalid target URL: %s", targetURL)
+	}
+
+	variants := []string{parsed.String()}
+	if !extended {
+		return variants, nil
+	}
+
+	hosts := []string{parsed.Host}
+	if alt := toggleWWW(parsed.Hostname()); alt != "" {
+		if port := parsed.Port(); port != "" {
+			alt = net.JoinHostPort(alt, port)
+		}
+		hosts = append(hosts, alt)
+	}
+
+	schemes := []string{parsed.Scheme}
+	switch parsed.Scheme {
+	case "https":
+		schemes = append(schemes, "http")
+	case "http":
+		schemes = append(schemes, "https")
+	}
+
+	seen := map[string]bool{variants[0]: true}
+	for _, scheme := range schemes {
+		for _, host := range hosts {
+			variant := *parsed
+			variant.Scheme = scheme
+			variant.Host = host
+			if v := variant.String(); !seen[v] {
+				seen[v] = true
+				variants = append(variants, v)
+			}
+		}
+	}
+
+	return variants, nil
+}
+
+// Return the www/apex counterpart of a host, or "" when there is none.
+// IP addresses have no counterpart, and deeper subdomains are left alone
+// because the apex cannot be derived without a public suffix list.
+func toggleWWW(host string) string {
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+
+	host = strings.ToLower(host)
+	if strings.HasPrefix(host, "www.") {
+		return strings.TrimPrefix(host, "www.")
+	}
+	if strings.Count(host, ".") == 1 {
+		return "www." + host
+	}
+	return ""
+}
+
+// Find, download and fingerprint the favicon served by one variant
+func (f *FaviconFinder) probeVariant(variantURL string) *VariantResult {
+	result := &VariantResult{URL: variantURL}
+
+	if f.config.AllCandidates {
+		icons, userAgent, err := f.enumerateIcons(variantURL)
+		result.UserAgent = userAgent
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to find favicon: %v", err)
+			return result
+		}
+		result.Icons = icons
+		result.FaviconURL = icons[0].URL
+		result.Fingerprint = icons[0].Fingerprint
+		result.favicon = icons[0].data
+		return result
+	}
+
+	// Try HTML detection first
+	faviconURL, inline, err := f.findFaviconInHTML(variantURL)
+	if err != nil {
+		f.debug("HTML detection failed: %v", err)
+		// Try common paths
+		faviconURL, err = f.checkCommonPaths(variantURL)
+	}
+	if err != nil && len(inline) > 0 {
+		// A page logo is rarely the favicon, so inline icons come last
+		f.debug("Trying %d inline icon candidates", len(inline))
+		if inlineURL := f.firstValidCandidate(inline); inlineURL != "" {
+			faviconURL, err = inlineURL, nil
+		}
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to find favicon: %v", err)
+		return result
+	}
+	result.FaviconURL = faviconURL
+
+	// Download and process favicon
+	faviconData, _, userAgent, err := f.downloadFavicon(faviconURL)
+	result.UserAgent = userAgent
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	fingerprint, err := calculateFingerprint(faviconData, f.hashProfile)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to calculate hash: %v", err)
+		return result
+	}
+	result.Fingerprint = fingerprint
+	result.favicon = faviconData
+
+	return result
+}
+
+// Check whether successful variants disagree on the favicon hash
+func variantsDiffer(variants []VariantResult) bool {
+	var first *Fingerprint
+	for i := range variants {
+		fp := variants[i].Fingerprint
+		if fp == nil {
+			continue
+		}
+		if first == nil {
+			first = fp
+		} else if fp.SHA256 != first.SHA256 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToggleWWW(t *testing.T) {
+	tests := map[string]string{
+		"example.com":            "www.example.com",
+		"www.example.com":        "example.com",
+		"WWW.Example.com":        "example.com",
+		"portal.example.com":     "",
+		"www.portal.example.com": "portal.example.com",
+		"localhost":              "",
+		"10.0.0.1":               "",
+		"::1":                    "",
+	}
+
+	for host, want := range tests {
+		if got := toggleWWW(host); got != want {
+			t.Errorf("toggleWWW(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestHostVariants(t *testing.T) {
+	tests := []struct {
+		target   string
+		extended bool
+		want     []string
+		wantErr  bool
+	}{
+		{target: "https://example.com", want: []string{"https://example.com"}},
+		{
+			target:   "https://example.com/login",
+			extended: true,
+			want: []string{
+				"https://example.com/login",
+				"https://www.example.com/login",
+				"http://example.com/login",
+				"http://www.example.com/login",
+			},
+		},
+		{
+			target:   "http://www.example.com:8080",
+			extended: true,
+			want: []string{
+				"http://www.example.com:8080",
+				"http://example.com:8080",
+				"https://www.example.com:8080",
+				"https://example.com:8080",
+			},
+		},
+		{
+			target:   "https://10.0.0.1",
+			extended: true,
+			want:     []string{"https://10.0.0.1", "http://10.0.0.1"},
+		},
+		{
+			target:   "https://portal.example.com",
+			extended: true,
+			want:     []string{"https://portal.example.com", "http://portal.example.com"},
+		},
+		{target: "example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := hostVariants(tt.target, tt.extended)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("hostVariants() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("hostVariants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const zoomeyePageSize = 20
+
+// zoomeyeEngine searches ZoomEye hosts by MMH3 hash
+type zoomeyeEngine struct {
+	engineBase
+}
+
+type zoomeyeResources struct {
+	Plan      string `json:"plan"`
+	QuotaInfo struct {
+		RemainTotalQuota int `json:"remain_total_quota"`
+	} `json:"quota_info"`
+}
+
+type zoomeyeName struct {
+	Names struct {
+		En string `json:"en"`
+	} `json:"names"`
+}
+
+type zoomeyeMatch struct {
+	IP       string `json:"ip"`
+	PortInfo struct {
+		Port      int         `json:"port"`
+		Hostname  string      `json:"hostname"`
+		Transport string      `json:"transport"`
+		App       string      `json:"app"`
+		Version   string      `json:"version"`
+		Title     interface{} `json:"title"` // string or list of strings
+	} `json:"portinfo"`
+	GeoInfo struct {
+		Country      zoomeyeName `json:"country"`
+		City         zoomeyeName `json:"city"`
+		ISP          string      `json:"isp"`
+		Organization string      `json:"organization"`
+		ASN          interface{} `json:"asn"`
+	} `json:"geoinfo"`
+	Timestamp string `json:"timestamp"`
+}
+
+type zoomeyeSearchResponse struct {
+	Total   int               `json:"total"`
+	Matches []json.RawMessage `json:"matches"`
+}
+
+func (e *zoomeyeEngine) get(path string, params url.Values, v interface{}) error {
+	reqURL := e.baseURL + path
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("API-KEY", e.key)
+	return e.f.fetchJSON(req, e.name, v)
+}
+
+func (e *zoomeyeEngine) ValidateKey() error {
+	var resources zoomeyeResources
+	return e.get("/resources-info", nil, &resources)
+}
+
+func (e *zoomeyeEngine) Quota() (*EngineQuota, error) {
+	var resources zoomeyeResources
+	if err := e.get("/resources-info", nil, &resources); err != nil {
+		return nil, err
+	}
+	return &EngineQuota{Plan: resources.Plan, Remaining: resources.QuotaInfo.RemainTotalQuota, Unit: "results"}, nil
+}
+
+func (e *zoomeyeEngine) Query(fp *Fingerprint) string {
+	return fmt.Sprintf(`iconhash:"%d"`, fp.MMH3)
+}
+
+func (e *zoomeyeEngine) ManualSearchURL(fp *Fingerprint) string {
+	return "https://www.zoomeye.org/searchResult?q=" + url.QueryEscape(e.Query(fp))
+}
+
+func (e *zoomeyeEngine) SearchHash(fp *Fingerprint) (*SearchResult, error) {
+	query := e.Query(fp)
+
+	return e.f.collectPages(zoomeyePageSize, func(page int) ([]Host, int, error) {
+		var resp zoomeyeSearchResponse
+		params := url.Values{"query": {query}, "page": {strconv.Itoa(page)}}
+		if err := e.get("/host/search", params, &resp); err != nil {
+			return nil, 0, err
+		}
+
+		var hosts []Host
+		for _, raw := range resp.Matches {
+			var match zoomeyeMatch
+			if err := json.Unmarshal(raw, &match); err != nil {
+				e.f.debug("Skipping undecodable ZoomEye match: %v", err)
+				continue
+			}
+
+			host := Host{
+				IP:         match.IP,
+				Port:       match.PortInfo.Port,
+				Transport:  match.PortInfo.Transport,
+				Org:        match.GeoInfo.Organization,
+				ASN:        formatASN(match.GeoInfo.ASN),
+				ISP:        match.GeoInfo.ISP,
+				Country:    match.GeoInfo.Country.Names.En,
+				City:       match.GeoInfo.City.Names.En,
+				Product:    match.PortInfo.App,
+				Version:    match.PortInfo.Version,
+				HTTPTitle:  firstString(match.PortInfo.Title),
+				LastUpdate: match.Timestamp,
+			}
+			if match.PortInfo.Hostname != "" {
+				host.Hostnames = []string{match.PortInfo.Hostname}
+			}
+			e.f.attachRaw(&host, e.name, raw)
+			hosts = append(hosts, host)
+		}
+		return hosts, resp.Total, nil
+	})
+}
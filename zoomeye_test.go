@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestZoomEyeSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("API-KEY") != "key" || r.URL.Query().Get("query") != `iconhash:"116323821"` {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		// The title comes as a string or a list, and the ASN as a number
+		fmt.Fprint(w, `{"total": 2, "matches": [{
+			"ip": "192.0.2.20",
+			"portinfo": {"port": 443, "hostname": "app.example.com", "transport": "tcp",
+				"app": "nginx", "version": "1.25", "title": ["Login", "Other"]},
+			"geoinfo": {"country": {"names": {"en": "Japan"}}, "city": {"names": {"en": "Tokyo"}},
+				"isp": "Example ISP", "organization": "Example KK", "asn": 64501},
+			"timestamp": "2024-05-01T00:00:00"
+		}, {
+			"ip": "192.0.2.21",
+			"portinfo": {"port": 80, "title": "Home"},
+			"geoinfo": {"asn": "AS64502"}
+		}]}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"zoomeye"},
+		EngineKeys: map[string]string{"zoomeye": "key"},
+		EngineURLs: map[string]string{"zoomeye": server.URL},
+		Pages:      3,
+	})
+
+	result, err := f.engines[0].SearchHash(&Fingerprint{MMH3: 116323821})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || len(result.Hosts) != 2 || result.Pages != 1 {
+		t.Fatalf("total %d, %d hosts over %d pages; want 2 hosts on one page", result.Total, len(result.Hosts), result.Pages)
+	}
+
+	first, second := result.Hosts[0], result.Hosts[1]
+	if first.Port != 443 || first.ASN != "AS64501" || first.Country != "Japan" || first.City != "Tokyo" {
+		t.Errorf("first host = %+v", first)
+	}
+	if first.HTTPTitle != "Login" || first.Product != "nginx" || first.Version != "1.25" {
+		t.Errorf("first host service = %q, %q, %q", first.HTTPTitle, first.Product, first.Version)
+	}
+	if len(first.Hostnames) != 1 || first.Hostnames[0] != "app.example.com" {
+		t.Errorf("hostnames = %v, want [app.example.com]", first.Hostnames)
+	}
+	if second.HTTPTitle != "Home" || second.ASN != "AS64502" || second.Hostnames != nil {
+		t.Errorf("second host = %+v", second)
+	}
+}
+
+func TestZoomEyeQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/resources-info" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"plan": "developer", "quota_info": {"remain_total_quota": 9800}}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"zoomeye"},
+		EngineKeys: map[string]string{"zoomeye": "key"},
+		EngineURLs: map[string]string{"zoomeye": server.URL},
+	})
+
+	quota, err := f.engines[0].Quota()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Plan != "developer" || quota.Remaining != 9800 || quota.Unit != "results" {
+		t.Errorf("quota = %+v, want developer with 9800 results", quota)
+	}
+}
+
+func TestZoomEyeAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": "bad_request", "message": "Invalid API key"}`)
+	}))
+	defer server.Close()
+
+	f := newTestFinder(t, &Config{
+		Engines:    []string{"zoomeye"},
+		EngineKeys: map[string]string{"zoomeye": "key"},
+		EngineURLs: map[string]string{"zoomeye": server.URL},
+	})
+
+	if _, err := f.engines[0].SearchHash(&Fingerprint{MMH3: 1}); err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("SearchHash() error = %v, want status 403", err)
+	}
+	if err := f.engines[0].ValidateKey(); err == nil || !strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("ValidateKey() error = %v, want the API message", err)
+	}
+}